@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+// passphraseEnv ... Environment variable holding the bundle passphrase.
+const passphraseEnv = "SSURI_PASSPHRASE"
+
+var packCommand = &command{
+	name:    "pack",
+	args:    "[-i in_file] [-o out_file] [-passphrase-file file]",
+	summary: "Encrypt a server list into a passphrase protected bundle.",
+	run:     runPack,
+}
+
+var unpackCommand = &command{
+	name:    "unpack",
+	args:    "[-i in_file] [-o out_file] [-passphrase-file file] [-flavor flavor]",
+	summary: "Decrypt a bundle written by pack.",
+	run:     runUnpack,
+}
+
+// addPassphraseFlag ... Register -passphrase-file.
+func addPassphraseFlag(fs *flag.FlagSet) *string {
+	return fs.String("passphrase-file", "", "file holding the bundle passphrase (default: $"+passphraseEnv+")")
+}
+
+// readPassphrase ... Read passphrase from file, or from the environment if name is empty.
+// Passphrases are never taken from the command line, where other users can see them.
+func readPassphrase(name string) (string, error) {
+	if name == "" {
+		passphrase := os.Getenv(passphraseEnv)
+		if passphrase == "" {
+			return "", errors.New("no passphrase, set $" + passphraseEnv + " or use -passphrase-file")
+		}
+
+		return passphrase, nil
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// runPack ... ssuri pack
+func runPack(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	passphraseFile := addPassphraseFlag(fs)
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return fail(err)
+	}
+
+	uris, err := readServers(files, false)
+	if err != nil {
+		return fail(err)
+	}
+
+	bundle, err := ss.SealBundle(uris, passphrase)
+	if err != nil {
+		return fail(err)
+	}
+
+	outputFile, closeOutput, err := files.openOutput()
+	if err != nil {
+		return fail(err)
+	}
+	defer closeOutput()
+
+	// Armor the bundle so it can be pasted into chat.
+	if _, err := fmt.Fprintf(outputFile, "%s\n", base64.StdEncoding.EncodeToString(bundle)); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
+
+// runUnpack ... ssuri unpack
+func runUnpack(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	passphraseFile := addPassphraseFlag(fs)
+	flavor := fs.String("flavor", "sip002", "output format: "+strings.Join(ss.EncoderFormats(), ", "))
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if !checkEncoderFormat(*flavor) {
+		return exitUsage
+	}
+
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return fail(err)
+	}
+
+	data, err := files.readInput()
+	if err != nil {
+		return fail(err)
+	}
+
+	// Chat clients may wrap long lines.
+	bundle, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+	if err != nil {
+		return fail(errors.New("invalid bundle"))
+	}
+
+	uris, err := ss.OpenBundle(bundle, passphrase)
+	if err != nil {
+		return fail(err)
+	}
+
+	encoded, err := ss.EncodeFormat(uris, *flavor)
+	if err != nil {
+		return fail(err)
+	}
+
+	outputFile, closeOutput, err := files.openOutput()
+	if err != nil {
+		return fail(err)
+	}
+	defer closeOutput()
+
+	if _, err := outputFile.Write(encoded); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Exit codes of ssuri.
+const (
+	exitOK    = 0 // Success
+	exitError = 1 // Runtime error, or problems found by lint
+	exitUsage = 2 // Invalid command line
+)
+
+// command ... A ssuri subcommand.
+type command struct {
+	name    string                              // Name used on the command line
+	args    string                              // Synopsis of flags and arguments
+	summary string                              // One line description
+	run     func(c *command, args []string) int // Runs command and returns exit code
+}
+
+// commands ... Available subcommands, in order of help.
+var commands = []*command{
+	decodeCommand,
+	encodeCommand,
+	convertCommand,
+	qrCommand,
+	tuiCommand,
+	lintCommand,
+	genCommand,
+	subCommand,
+	setCommand,
+	packCommand,
+	unpackCommand,
+	serveCommand,
+	probeCommand,
+	connectCommand,
+	testServerCommand,
+}
+
+// findCommand ... Look up a subcommand by name.
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// usage ... Print top level help.
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\n", os.Args[0] /* Program name */)
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+
+	width := 0
+	for _, c := range commands {
+		if len(c.name) > width {
+			width = len(c.name)
+		}
+	}
+
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-*s %s\n", width, c.name, c.summary)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nRun \"%s help <command>\" for flags of a command.\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Run \"%s -h\" for flags of the legacy interface.\n", os.Args[0])
+}
+
+// runHelp ... Print help of a command, or top level help.
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return exitOK
+	}
+
+	c := findCommand(args[0])
+	if c == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		usage()
+		return exitUsage
+	}
+
+	return c.run(c, []string{"-h"})
+}
+
+// newFlagSet ... Returns flag set of command printing its own usage.
+func newFlagSet(c *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s %s\n\n%s\n\n", os.Args[0], c.name, c.args, c.summary)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// parseFlags ... Parse flags of command, returns exit code and false if the command should stop.
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	return parseFlagsAndArgs(fs, args, 0)
+}
+
+// parseFlagsAndArgs ... Like parseFlags, but allows up to maxArgs arguments after the flags.
+func parseFlagsAndArgs(fs *flag.FlagSet, args []string, maxArgs int) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+
+		return exitUsage, false
+	}
+
+	if fs.NArg() > maxArgs {
+		fmt.Fprintf(fs.Output(), "unexpected argument %q\n", fs.Arg(maxArgs))
+		fs.Usage()
+
+		return exitUsage, false
+	}
+
+	return exitOK, true
+}
+
+// ioFlags ... Input and output flags shared by commands.
+type ioFlags struct {
+	input  *string
+	output *string
+}
+
+// addIOFlags ... Register -i and -o.
+func addIOFlags(fs *flag.FlagSet) *ioFlags {
+	return &ioFlags{
+		input:  fs.String("i", "-", "input file, \"-\" for stdin"),
+		output: fs.String("o", "-", "output file, \"-\" for stdout"),
+	}
+}
+
+// readInput ... Read whole input with surrounding spaces trimmed.
+func (f *ioFlags) readInput() (string, error) {
+	data, _, err := readInputFile(*f.input)
+	return data, err
+}
+
+// readInputAt ... Like readInput, also returns the input line the data starts at.
+func (f *ioFlags) readInputAt() (string, int, error) {
+	return readInputFile(*f.input)
+}
+
+// openOutput ... Open output, the returned function closes it.
+func (f *ioFlags) openOutput() (*os.File, func(), error) {
+	return openOutputFile(*f.output)
+}
+
+// readInputFile ... Read whole file, "-" for stdin, with surrounding spaces
+// trimmed, see trimInput.
+func readInputFile(name string) (string, int, error) {
+	inputFile := os.Stdin
+
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return "", 0, err
+		}
+
+		defer f.Close()
+
+		inputFile = f
+	}
+
+	data, err := ioutil.ReadAll(inputFile)
+	if err != nil {
+		return "", 0, err
+	}
+
+	trimmed, firstLine := trimInput(string(data))
+
+	return trimmed, firstLine, nil
+}
+
+// trimInput ... Trim surrounding spaces of input data, returns the trimmed data
+// and the 1-based line of the input it starts at.
+func trimInput(data string) (string, int) {
+	trimmed := strings.TrimLeftFunc(data, unicode.IsSpace)
+	firstLine := 1 + strings.Count(data[:len(data)-len(trimmed)], "\n")
+
+	return strings.TrimRightFunc(trimmed, unicode.IsSpace), firstLine
+}
+
+// openOutputFile ... Create file, "-" for stdout, the returned function closes it.
+func openOutputFile(name string) (*os.File, func(), error) {
+	if name == "-" {
+		return os.Stdout, func() {}, nil
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, func() { f.Close() }, nil
+}
+
+// fail ... Print error and return exitError.
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "%v\n", err)
+	return exitError
+}
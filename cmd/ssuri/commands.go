@@ -0,0 +1,816 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+var decodeCommand = &command{
+	name:    "decode",
+	args:    "[-i in_file] [-o out_file] [-format format] [-redact mode]",
+	summary: "Decode shadowsocks URIs or a subscription and dump the servers.",
+	run:     runDecode,
+}
+
+var encodeCommand = &command{
+	name:    "encode",
+	args:    "[-i in_file] [-o out_file] [-flavor flavor] [-watch [-interval duration] [-qr]]",
+	summary: "Encode a JSON client configuration as shadowsocks URI.",
+	run:     runEncode,
+}
+
+var convertCommand = &command{
+	name:    "convert",
+	args:    "[-i in_file] [-o out_file] -from format -to format [-watch [-interval duration] [-qr]] [client settings]",
+	summary: "Convert servers between any two registered formats.",
+	run:     runConvert,
+}
+
+var qrCommand = &command{
+	name:    "qr",
+	args:    "[-i in_file] [-o out_file | -output-dir dir] [-json] [-legacy] [-redact mode]",
+	summary: "Print QR codes of shadowsocks URIs.",
+	run:     runQR,
+}
+
+var lintCommand = &command{
+	name:    "lint",
+	args:    "[-i in_file] [-o out_file] [-from format] [-format text|json] [-level severity] [-fail-on severity] [-resolve]",
+	summary: "Audit servers for insecure or broken settings, exits with 1 if problems are found.",
+	run:     runLint,
+}
+
+var genCommand = &command{
+	name:    "gen",
+	args:    "[-i in_file] [-o out_file | -output-dir dir] [-legacy] [-watch [-interval duration] [-qr]] [client settings]",
+	summary: "Generate JSON client configuration from shadowsocks URIs.",
+	run:     runGen,
+}
+
+var subCommand = &command{
+	name:    "sub",
+	args:    "[-i in_file] [-o out_file] [-dedupe] [-filter spec] [-sort fields] [-rename template] [-b64]",
+	summary: "Process a subscription: deduplicate, filter, sort and rename servers.",
+	run:     runSub,
+}
+
+var serveCommand = &command{
+	name:    "serve",
+	args:    "[-i in_file] [-listen address] [-token token] [-redact mode]",
+	summary: "Serve servers over HTTP as subscription, SIP008 document and QR codes.",
+	run:     runServe,
+}
+
+var probeCommand = &command{
+	name:    "probe",
+	args:    "[-i in_file] [-o out_file] [-url url] [-timeout duration] [-workers n] [-format text|json] [-redact mode]",
+	summary: "Check which servers are alive and measure their latency, exits with 1 if any is down.",
+	run:     runProbe,
+}
+
+var connectCommand = &command{
+	name:    "connect",
+	args:    "[-i in_file] [-json] [-n index] [client settings] [uri]",
+	summary: "Run a local SOCKS5 proxy tunneling through a shadowsocks server.",
+	run:     runConnect,
+}
+
+var testServerCommand = &command{
+	name:    "test-server",
+	args:    "[-i in_file] [-n index] [-bind host] [-mode mode] [uri]",
+	summary: "Run a minimal shadowsocks server with the method, password and port of a URI.",
+	run:     runTestServer,
+}
+
+// runDecode ... ssuri decode
+func runDecode(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	format := fs.String("format", "text", "output format: "+strings.Join(ss.DumpFormats, ", "))
+	redact := addRedactFlag(fs)
+	decodeOpts := addDecodeOptionFlags(fs)
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	data, err := files.readInput()
+	if err != nil {
+		return fail(err)
+	}
+
+	uris, err := ss.DecodeFormatWithOptions([]byte(data), "subscription", decodeOpts)
+	if err == nil {
+		uris, err = redactServers(uris, *redact, false)
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	outputFile, closeOutput, err := files.openOutput()
+	if err != nil {
+		return fail(err)
+	}
+	defer closeOutput()
+
+	if err := dumpShadowsocksURI(uris, *format, outputFile); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
+
+// runEncode ... ssuri encode
+func runEncode(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	flavor := fs.String("flavor", "sip002", "output format: "+strings.Join(ss.EncoderFormats(), ", "))
+	redact := addRedactFlag(fs)
+	watch := addWatchFlags(fs)
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if !watch.check(fs) {
+		return exitUsage
+	}
+
+	return convertFile(files, "client-json", *flavor, nil, nil, *redact, watch)
+}
+
+// runConvert ... ssuri convert
+func runConvert(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	from := fs.String("from", "uri", "input format: "+strings.Join(ss.DecoderFormats(), ", "))
+	to := fs.String("to", "client-json", "output format: "+strings.Join(ss.EncoderFormats(), ", "))
+	clientOpts := addClientOptionFlags(fs)
+	decodeOpts := addDecodeOptionFlags(fs)
+	redact := addRedactFlag(fs)
+	watch := addWatchFlags(fs)
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if !watch.check(fs) {
+		return exitUsage
+	}
+
+	opts, err := clientOpts.resolve()
+	if err != nil {
+		return fail(err)
+	}
+
+	return convertFile(files, *from, *to, opts, decodeOpts, *redact, watch)
+}
+
+// runQR ... ssuri qr
+func runQR(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	dir := addOutputDirFlags(fs)
+	jsonInput := fs.Bool("json", false, "read JSON client configuration as input")
+	legacy := fs.Bool("legacy", false, "encode legacy base64 URIs")
+	redact := addRedactFlag(fs)
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	uris, err := readServers(files, *jsonInput)
+	if err == nil {
+		uris, err = redactServers(uris, *redact, true)
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	write := func(w io.Writer, i int, uri *ss.ShadowsocksURI) error {
+		generateShadowsocksQRCode(uri, *legacy, w)
+		return nil
+	}
+
+	if err := dir.write(files, ".qr.txt", uris, write); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
+
+// runLint ... ssuri lint
+func runLint(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	from := fs.String("from", "uri", "input format: "+strings.Join(ss.DecoderFormats(), ", "))
+	format := fs.String("format", "text", "output format: text or json")
+	level := fs.String("level", "info", "lowest severity to report: info, warning or error")
+	failOn := fs.String("fail-on", "warning", "lowest severity exiting with 1: info, warning or error")
+	resolve := fs.Bool("resolve", false, "resolve hostnames to check for private addresses")
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if !checkDecoderFormat(*from) {
+		return exitUsage
+	}
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "invalid output format %q, available: text, json\n", *format)
+		return exitUsage
+	}
+
+	minSeverity, err := ss.ParseSeverity(*level)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	failSeverity, err := ss.ParseSeverity(*failOn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	data, firstLine, err := files.readInputAt()
+	if err != nil {
+		return fail(err)
+	}
+
+	var uris []*ss.ShadowsocksURI
+
+	if *from == "uri" {
+		// Report every malformed line instead of stopping at the first one.
+		uris, err = decodeLintLines(data, firstLine)
+	} else {
+		uris, err = ss.DecodeFormat([]byte(data), *from)
+	}
+
+	opts := &ss.LintOptions{}
+	if *resolve {
+		opts.LookupHost = net.LookupHost
+	}
+
+	findings := []*ss.Finding{}
+
+	for _, f := range ss.Lint(uris, opts) {
+		if f.Severity >= minSeverity {
+			findings = append(findings, f)
+		}
+	}
+
+	outputFile, closeOutput, openErr := files.openOutput()
+	if openErr != nil {
+		return fail(openErr)
+	}
+	defer closeOutput()
+
+	if *format == "json" {
+		encoder := json.NewEncoder(outputFile)
+		encoder.SetIndent("", "    ")
+		if err := encoder.Encode(findings); err != nil {
+			return fail(err)
+		}
+	} else {
+		for _, f := range findings {
+			if _, err := fmt.Fprintln(outputFile, f); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	if err != nil {
+		// Malformed input is always an error.
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+
+	if len(findings) != 0 && ss.MaxSeverity(findings) >= failSeverity {
+		return exitError
+	}
+
+	return exitOK
+}
+
+// decodeLintLines ... Decode a subscription starting at firstLine of the input,
+// returning the valid servers and an error listing every malformed line. Lines
+// of a base64 subscription are counted after decoding it.
+func decodeLintLines(data string, firstLine int) ([]*ss.ShadowsocksURI, error) {
+	text, err := ss.DecodeSubscriptionText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if text != data {
+		firstLine = 1
+	}
+
+	uris := []*ss.ShadowsocksURI{}
+	problems := []string{}
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		uri, err := ss.DecodeURI(line)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", firstLine+i, err))
+			continue
+		}
+
+		uris = append(uris, uri)
+	}
+
+	if len(problems) != 0 {
+		return uris, errors.New(strings.Join(problems, "\n"))
+	}
+
+	return uris, nil
+}
+
+// runGen ... ssuri gen
+func runGen(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	dir := addOutputDirFlags(fs)
+	clientOpts := addClientOptionFlags(fs)
+	legacy := fs.Bool("legacy", false, "omit plugin fields from JSON")
+	redact := addRedactFlag(fs)
+	watch := addWatchFlags(fs)
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if !watch.check(fs) {
+		return exitUsage
+	}
+
+	opts, err := clientOpts.resolve()
+	if err != nil {
+		return fail(err)
+	}
+
+	write := func(w io.Writer, i int, uri *ss.ShadowsocksURI) error {
+		data, err := ss.EncodeClientJSON(generateShadowsocksClientConfig(uri, opts), *legacy)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", data)
+
+		return err
+	}
+
+	generate := func(data string, _ int) ([]*ss.ShadowsocksURI, error) {
+		uris, err := decodeServers(data, false)
+		if err == nil {
+			uris, err = redactServers(uris, *redact, false)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return uris, dir.write(files, ".json", uris, write)
+	}
+
+	redactMode, err := ss.ParseRedactMode(*redact)
+	if err != nil {
+		return fail(err)
+	}
+
+	return runOnceOrWatch(files, watch, redactMode, generate)
+}
+
+// runSub ... ssuri sub
+func runSub(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	dedupe := fs.Bool("dedupe", false, "deduplicate servers")
+	tagPolicy := fs.String("tag-policy", "first", "tag merging policy for -dedupe: first, last, join or longest")
+	b64 := fs.Bool("b64", false, "write a base64 encoded subscription")
+	flavor := fs.String("flavor", "sip002", "output format, ignored by -b64: "+strings.Join(ss.EncoderFormats(), ", "))
+	redact := addRedactFlag(fs)
+
+	var pipeline ss.Pipeline
+	addPipelineFlags(fs, &pipeline)
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if !checkEncoderFormat(*flavor) {
+		return exitUsage
+	}
+
+	data, firstLine, err := files.readInputAt()
+	if err != nil {
+		return fail(err)
+	}
+
+	uris, err := ss.DecodeSubscription(data)
+	if err != nil {
+		return fail(err)
+	}
+
+	if *b64 {
+		*flavor = "subscription"
+	}
+
+	uris, err = processServers(uris, uriLines(data, firstLine), *dedupe, *tagPolicy, pipeline)
+	if err == nil {
+		uris, err = redactServers(uris, *redact, uriFormats[*flavor])
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	outputFile, closeOutput, err := files.openOutput()
+	if err != nil {
+		return fail(err)
+	}
+	defer closeOutput()
+
+	encoded, err := ss.EncodeFormat(uris, *flavor)
+	if err != nil {
+		return fail(err)
+	}
+
+	if _, err := outputFile.Write(encoded); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
+
+// runServe ... ssuri serve
+func runServe(c *command, args []string) int {
+	fs := newFlagSet(c)
+	input := fs.String("i", "-", "input file, \"-\" for stdin")
+	listen := fs.String("listen", "127.0.0.1:8080", "address to listen on")
+	token := fs.String("token", "", "serve only below /<token>/")
+	redact := fs.String("redact", "none", "strip passwords and plugin secrets from everything served: none, partial or full")
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if strings.Contains(*token, "/") {
+		fmt.Fprintf(os.Stderr, "invalid token %q\n", *token)
+		return exitUsage
+	}
+
+	uris, err := readServers(&ioFlags{input: input}, false)
+	if err == nil {
+		uris, err = redactServers(uris, *redact, true)
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	prefix := "/"
+	if *token != "" {
+		prefix += *token + "/"
+	}
+
+	fmt.Fprintf(os.Stderr, "Serving %d servers on http://%s%s\n", len(uris), *listen, prefix)
+
+	return fail(http.ListenAndServe(*listen, ss.NewSubscriptionHandler(uris, *token)))
+}
+
+// probeRecord ... JSON output of probe.
+type probeRecord struct {
+	Index     int    `json:"index"`
+	Tag       string `json:"tag"`
+	Server    string `json:"server"`
+	Alive     bool   `json:"alive"`
+	ConnectMs int64  `json:"connect_ms"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Status    int    `json:"status,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// runProbe ... ssuri probe
+func runProbe(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	url := fs.String("url", "", "fetch url through each server, e.g. http://www.gstatic.com/generate_204 (default: TCP connect only)")
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout of each step")
+	workers := fs.Int("workers", 8, "servers probed at once")
+	format := fs.String("format", "text", "output format: text or json")
+	redact := fs.String("redact", "none", "mask passwords quoted by errors: none, partial or full")
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "invalid output format %q, available: text, json\n", *format)
+		return exitUsage
+	}
+
+	redactMode, err := ss.ParseRedactMode(*redact)
+	if err != nil {
+		return fail(err)
+	}
+
+	uris, err := readServers(files, false)
+	if err != nil {
+		return fail(err)
+	}
+
+	results := ss.Probe(uris, &ss.ProbeOptions{Timeout: *timeout, Workers: *workers, URL: *url})
+
+	outputFile, closeOutput, err := files.openOutput()
+	if err != nil {
+		return fail(err)
+	}
+	defer closeOutput()
+
+	code := exitOK
+	records := []*probeRecord{}
+
+	for _, r := range results {
+		record := &probeRecord{
+			Index:     r.Index + 1,
+			Tag:       r.URI.Tag,
+			Server:    r.URI.Remote.String(),
+			Alive:     r.Alive(),
+			ConnectMs: r.Connect.Milliseconds(),
+			LatencyMs: r.Latency.Milliseconds(),
+			Status:    r.Status,
+		}
+
+		if r.Err != nil {
+			record.Error = r.Err.Error()
+			code = exitError
+
+			if password := r.URI.Auth.Password(); password != "" && redactMode != ss.RedactNone {
+				record.Error = strings.ReplaceAll(record.Error, password, ss.RedactSecret(password, redactMode))
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(outputFile)
+		encoder.SetIndent("", "    ")
+		if err := encoder.Encode(records); err != nil {
+			return fail(err)
+		}
+
+		return code
+	}
+
+	for _, r := range records {
+		var err error
+
+		switch {
+		case !r.Alive:
+			_, err = fmt.Fprintf(outputFile, "#%d %s (%s): down: %s\n", r.Index, r.Tag, r.Server, r.Error)
+		case *url == "":
+			_, err = fmt.Fprintf(outputFile, "#%d %s (%s): connect %dms\n", r.Index, r.Tag, r.Server, r.ConnectMs)
+		default:
+			_, err = fmt.Fprintf(outputFile, "#%d %s (%s): connect %dms, HTTP %d in %dms\n", r.Index, r.Tag, r.Server, r.ConnectMs, r.Status, r.LatencyMs)
+		}
+
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	return code
+}
+
+// runConnect ... ssuri connect
+func runConnect(c *command, args []string) int {
+	fs := newFlagSet(c)
+	input := fs.String("i", "-", "input file, \"-\" for stdin, ignored if a URI is given")
+	jsonInput := fs.Bool("json", false, "read JSON client configuration as input")
+	index := fs.Int("n", 1, "server of a list to connect to, from 1")
+	clientOpts := addClientOptionFlags(fs)
+
+	if code, ok := parseFlagsAndArgs(fs, args, 1); !ok {
+		return code
+	}
+
+	opts, err := clientOpts.resolve()
+	if err != nil {
+		return fail(err)
+	}
+
+	var scc *ss.ShadowsocksClientConfig
+
+	if *jsonInput && fs.NArg() == 0 {
+		data, _, err := readInputFile(*input)
+		if err != nil {
+			return fail(err)
+		}
+
+		scc, err = decodeJSONConfig([]byte(data), opts)
+		if err != nil {
+			return fail(err)
+		}
+	} else {
+		uri, err := selectServer(fs, *input, *index)
+		if err != nil {
+			return fail(err)
+		}
+
+		scc = generateShadowsocksClientConfig(uri, opts)
+	}
+
+	client, err := ss.NewShadowsocksClient(scc)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "SOCKS5 proxy on %s through %s\n", scc.Local.String(), scc.Remote.String())
+
+	return fail(client.ListenAndServe())
+}
+
+// runTestServer ... ssuri test-server
+func runTestServer(c *command, args []string) int {
+	fs := newFlagSet(c)
+	input := fs.String("i", "-", "input file, \"-\" for stdin, ignored if a URI is given")
+	index := fs.Int("n", 1, "server of a list to run, from 1")
+	bind := fs.String("bind", "127.0.0.1", "host to listen on, \"\" for every interface")
+	mode := fs.String("mode", ss.ModeTCPAndUDP, "relay mode: tcp_only, udp_only or tcp_and_udp")
+
+	if code, ok := parseFlagsAndArgs(fs, args, 1); !ok {
+		return code
+	}
+
+	uri, err := selectServer(fs, *input, *index)
+	if err != nil {
+		return fail(err)
+	}
+
+	server, err := ss.NewShadowsocksServer(uri)
+	if err != nil {
+		return fail(err)
+	}
+
+	server.Mode = *mode
+
+	fmt.Fprintf(os.Stderr, "Shadowsocks %s server on %s\n", uri.Auth.Method(), net.JoinHostPort(*bind, strconv.Itoa(uri.Remote.Port())))
+
+	return fail(server.ListenAndServe(*bind))
+}
+
+// selectServer ... Returns server given as argument, or the index-th server of input.
+func selectServer(fs *flag.FlagSet, input string, index int) (*ss.ShadowsocksURI, error) {
+	if fs.NArg() == 1 {
+		return ss.DecodeURI(fs.Arg(0))
+	}
+
+	uris, err := readServers(&ioFlags{input: &input}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if index < 1 || index > len(uris) {
+		return nil, fmt.Errorf("no server #%d in input of %d servers", index, len(uris))
+	}
+
+	return uris[index-1], nil
+}
+
+// readServers ... Read servers from a JSON client configuration or a subscription.
+func readServers(files *ioFlags, jsonInput bool) ([]*ss.ShadowsocksURI, error) {
+	data, err := files.readInput()
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeServers(data, jsonInput)
+}
+
+// decodeServers ... Decode servers of a JSON client configuration or a subscription.
+func decodeServers(data string, jsonInput bool) ([]*ss.ShadowsocksURI, error) {
+	if jsonInput {
+		clientConfig, err := decodeJSONConfig([]byte(data), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*ss.ShadowsocksURI{generateShadowsocksURI(clientConfig)}, nil
+	}
+
+	uris, err := ss.DecodeSubscription(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(uris) == 0 {
+		return nil, errors.New("no server in input")
+	}
+
+	return uris, nil
+}
+
+// convertFile ... Convert input file between registered formats, masking
+// secrets by the named redaction mode. Client JSON output uses opts if not nil,
+// input is checked by decodeOpts if not nil. With -watch the conversion is
+// repeated whenever the input changes.
+func convertFile(files *ioFlags, from, to string, opts *ss.ClientOptions, decodeOpts *ss.DecodeOptions,
+	redact string, watch *watchFlags) int {
+	if !checkDecoderFormat(from) || !checkEncoderFormat(to) {
+		return exitUsage
+	}
+
+	convert := func(data string, _ int) ([]*ss.ShadowsocksURI, error) {
+		uris, err := ss.DecodeFormatWithOptions([]byte(data), from, decodeOpts)
+		if err == nil {
+			uris, err = redactServers(uris, redact, uriFormats[to])
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		var encoded []byte
+
+		if to == "client-json" && opts != nil {
+			encoded, err = ss.NewClientJSONEncoder(opts).Encode(uris)
+		} else {
+			encoded, err = ss.EncodeFormat(uris, to)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return uris, writeOutput(*files.output, encoded)
+	}
+
+	redactMode, err := ss.ParseRedactMode(redact)
+	if err != nil {
+		return fail(err)
+	}
+
+	return runOnceOrWatch(files, watch, redactMode, convert)
+}
+
+// runOnceOrWatch ... Run update on the input, or with -watch on every change
+// of it. Servers redacted by redact have their secrets stripped from QR codes.
+func runOnceOrWatch(files *ioFlags, watch *watchFlags, redact ss.RedactMode, update updateFunc) int {
+	if *watch.watch {
+		return watch.run(files, redact, update)
+	}
+
+	data, firstLine, err := files.readInputAt()
+	if err != nil {
+		return fail(err)
+	}
+
+	if _, err := update(data, firstLine); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
+
+// checkDecoderFormat ... Check that name is a registered input format, printing an error otherwise.
+func checkDecoderFormat(name string) bool {
+	if f, ok := ss.LookupFormat(name); ok && f.Decoder != nil {
+		return true
+	}
+
+	fmt.Fprintf(os.Stderr, "invalid input format %q, available: %s\n", name, strings.Join(ss.DecoderFormats(), ", "))
+
+	return false
+}
+
+// checkEncoderFormat ... Check that name is a registered output format, printing an error otherwise.
+func checkEncoderFormat(name string) bool {
+	if f, ok := ss.LookupFormat(name); ok && f.Encoder != nil {
+		return true
+	}
+
+	fmt.Fprintf(os.Stderr, "invalid output format %q, available: %s\n", name, strings.Join(ss.EncoderFormats(), ", "))
+
+	return false
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+// legacyOptions ... Flags of the flat, pre-subcommand interface.
+type legacyOptions struct {
+	inputFileName      *string            // input file name, option -i, default stdin
+	outputFileName     *string            // output file name, option -o, default stdout
+	jsonMode           *bool              // run in JSON mode, option -json, default off
+	dumpURI            *bool              // dump URI information
+	dumpFormat         *string            // format of -dump-uri, option -dump-format, default text
+	legacyMode         *bool              // dump shadowsocks URI in legacy mode, option -legacy, default off
+	generateJSONConfig *bool              // generate JSON config, option -generate-json-config
+	generateQRCode     *bool              // generate QR code, option -generate-qr.
+	generateURI        *bool              // generate URI.
+	dedupe             *bool              // deduplicate a list of URIs, option -dedupe
+	tagPolicy          *string            // tag merging policy used by -dedupe
+	pipeline           ss.Pipeline        // stages of -filter, -sort and -rename in order
+	clientOptions      *clientOptionFlags // client settings of -generate-json-config
+	dumpOutput         *string            // output of -dump-uri, default -o
+	jsonOutput         *string            // output of -generate-json-config, default -o
+	qrOutput           *string            // output of -generate-qr, default -o
+	uriOutput          *string            // output of -generate-uri, default -o
+	outputDir          *string            // write one file per server and artifact into this directory
+	nameTemplate       *string            // template of per-server file names in -output-dir
+	redact             *string            // mask secrets in every output, option -redact
+	watch              *watchFlags        // rewrite the outputs whenever -i changes
+}
+
+// legacyArtifact ... One kind of output of the legacy interface.
+type legacyArtifact struct {
+	enabled bool
+	output  string // destination file, "-" for stdout
+	suffix  string // file name suffix in -output-dir
+	write   func(w io.Writer, uris []*ss.ShadowsocksURI, configs []*ss.ShadowsocksClientConfig) error
+}
+
+// newLegacyFlagSet ... Returns flag set of the legacy interface bound to opts.
+func newLegacyFlagSet(opts *legacyOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	opts.inputFileName = fs.String("i", "-", "input file (default: \"-\" for stdin)")
+	opts.outputFileName = fs.String("o", "-", "output file (default: \"-\" for stdout)")
+	opts.jsonMode = fs.Bool("json", false, "read JSON as input (default: off)")
+	opts.dumpURI = fs.Bool("dump-uri", false, "dump shadowsocks URI")
+	opts.dumpFormat = fs.String("dump-format", "text", "format of -dump-uri: "+strings.Join(ss.DumpFormats, ", "))
+	opts.legacyMode = fs.Bool("legacy", false, "dump shadowsocks URI in legacy mode (default: off)")
+	opts.generateJSONConfig = fs.Bool("generate-json-config", false, "generate JSON configurations")
+	opts.generateQRCode = fs.Bool("generate-qr", false, "generate QR code")
+	opts.generateURI = fs.Bool("generate-uri", false, "generate URI")
+	opts.dedupe = fs.Bool("dedupe", false, "deduplicate a list of URIs, one per line")
+	opts.tagPolicy = fs.String("tag-policy", "first", "tag merging policy for -dedupe: first, last, join or longest")
+	opts.dumpOutput = fs.String("dump-o", "", "output file of -dump-uri (default: -o)")
+	opts.jsonOutput = fs.String("json-o", "", "output file of -generate-json-config (default: -o)")
+	opts.qrOutput = fs.String("qr-o", "", "output file of -generate-qr (default: -o)")
+	opts.uriOutput = fs.String("uri-o", "", "output file of -generate-uri (default: -o)")
+	opts.outputDir = fs.String("output-dir", "", "write one file per server and output into this directory")
+	opts.nameTemplate = fs.String("name-template", defaultNameTemplate, "template of file names in -output-dir, see -rename")
+	opts.redact = addRedactFlag(fs)
+	opts.watch = addWatchFlags(fs)
+	addPipelineFlags(fs, &opts.pipeline)
+	opts.clientOptions = addClientOptionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Printf("Usage: %s [-h] [-i in_file] [-o out_file]\n", os.Args[0] /* Program name */)
+		fmt.Printf("       %s <command> [flags], see \"%s help\"\n", os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// runLegacy ... Run the flat flag interface kept for compatibility.
+func runLegacy(args []string) int {
+	opts := &legacyOptions{}
+	fs := newLegacyFlagSet(opts)
+	fs.Parse(args)
+
+	if !opts.watch.check(fs) {
+		return exitUsage
+	}
+
+	clientOpts, err := opts.clientOptions.resolve()
+	if err != nil {
+		return fail(err)
+	}
+
+	redactMode, err := ss.ParseRedactMode(*opts.redact)
+	if err != nil {
+		return fail(err)
+	}
+
+	files := &ioFlags{input: opts.inputFileName, output: opts.outputFileName}
+
+	return runOnceOrWatch(files, opts.watch, redactMode, func(data string, firstLine int) ([]*ss.ShadowsocksURI, error) {
+		return writeLegacyOutputs(opts, clientOpts, redactMode, data, firstLine)
+	})
+}
+
+// writeLegacyOutputs ... Decode data and write every artifact asked for,
+// returns the servers.
+func writeLegacyOutputs(opts *legacyOptions, clientOpts *ss.ClientOptions, redactMode ss.RedactMode,
+	data string, firstLine int) ([]*ss.ShadowsocksURI, error) {
+	// More than one URI, one per line, is processed in batch mode.
+	batchMode := !*opts.jsonMode && strings.Contains(data, "\n")
+	batchMode = batchMode || *opts.dedupe || len(opts.pipeline) != 0
+
+	var uris []*ss.ShadowsocksURI
+	var configs []*ss.ShadowsocksClientConfig
+
+	if *opts.jsonMode {
+		// Read JSON configuration.
+		clientConfig, err := decodeJSONConfig([]byte(data), clientOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		clientConfig = clientConfig.Redact(redactMode)
+		uris = []*ss.ShadowsocksURI{generateShadowsocksURI(clientConfig)}
+		configs = []*ss.ShadowsocksClientConfig{clientConfig}
+	} else {
+		var err error
+
+		if batchMode {
+			uris, err = ss.DecodeURIList(data)
+			if err == nil {
+				uris, err = processServers(uris, uriLines(data, firstLine), *opts.dedupe, *opts.tagPolicy, opts.pipeline)
+			}
+		} else {
+			// Read shadowsocks URI.
+			var uri *ss.ShadowsocksURI
+			uri, err = decodeURI(data, *opts.legacyMode)
+			uris = []*ss.ShadowsocksURI{uri}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i, uri := range uris {
+			uris[i] = uri.Redact(redactMode)
+			configs = append(configs, generateShadowsocksClientConfig(uris[i], clientOpts))
+		}
+	}
+
+	artifacts := newLegacyArtifacts(opts, batchMode, redactMode)
+
+	if *opts.outputDir != "" {
+		return uris, writeLegacyArtifactsPerServer(opts, artifacts, uris, configs)
+	}
+
+	return uris, writeLegacyArtifacts(artifacts, uris, configs)
+}
+
+// newLegacyArtifacts ... Returns artifacts in order of output. Servers are
+// masked by redactMode already, URIs and QR codes strip their secrets.
+func newLegacyArtifacts(opts *legacyOptions, batchMode bool, redactMode ss.RedactMode) []*legacyArtifact {
+	destination := func(output string) string {
+		if output == "" {
+			return *opts.outputFileName
+		}
+
+		return output
+	}
+
+	generateURI := *opts.generateURI
+
+	// A list of URIs is written back as URIs unless something else was asked for.
+	if batchMode && !*opts.dumpURI && !*opts.generateJSONConfig && !*opts.generateQRCode {
+		generateURI = true
+	}
+
+	// -legacy selects the input scheme of a single URI, but the output scheme of a list.
+	legacyURI := batchMode && *opts.legacyMode
+
+	return []*legacyArtifact{
+		{
+			enabled: *opts.dumpURI,
+			output:  destination(*opts.dumpOutput),
+			suffix:  ".dump." + *opts.dumpFormat,
+			write: func(w io.Writer, uris []*ss.ShadowsocksURI, configs []*ss.ShadowsocksClientConfig) error {
+				return dumpShadowsocksURI(uris, *opts.dumpFormat, w)
+			},
+		},
+		{
+			enabled: *opts.generateJSONConfig,
+			output:  destination(*opts.jsonOutput),
+			suffix:  ".json",
+			write: func(w io.Writer, uris []*ss.ShadowsocksURI, configs []*ss.ShadowsocksClientConfig) error {
+				for _, scc := range configs {
+					if err := generateClientJSONConfig(scc, w); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+		},
+		{
+			enabled: *opts.generateQRCode,
+			output:  destination(*opts.qrOutput),
+			suffix:  ".qr.txt",
+			write: func(w io.Writer, uris []*ss.ShadowsocksURI, configs []*ss.ShadowsocksClientConfig) error {
+				for _, uri := range uris {
+					generateShadowsocksQRCode(redactServer(uri, redactMode, true), legacyURI, w)
+				}
+
+				return nil
+			},
+		},
+		{
+			enabled: generateURI,
+			output:  destination(*opts.uriOutput),
+			suffix:  ".uri",
+			write: func(w io.Writer, uris []*ss.ShadowsocksURI, configs []*ss.ShadowsocksClientConfig) error {
+				for _, uri := range uris {
+					fmt.Fprintf(w, "%s\n", encodeURI(redactServer(uri, redactMode, true), legacyURI))
+				}
+
+				return nil
+			},
+		},
+	}
+}
+
+// writeLegacyArtifacts ... Write each enabled artifact to its destination.
+func writeLegacyArtifacts(artifacts []*legacyArtifact, uris []*ss.ShadowsocksURI, configs []*ss.ShadowsocksClientConfig) error {
+	var files outputFiles
+
+	for _, a := range artifacts {
+		if !a.enabled {
+			continue
+		}
+
+		if err := a.write(files.open(a.output), uris, configs); err != nil {
+			return err
+		}
+	}
+
+	return files.commit()
+}
+
+// writeLegacyArtifactsPerServer ... Write each enabled artifact of each server to its own file in -output-dir.
+func writeLegacyArtifactsPerServer(opts *legacyOptions, artifacts []*legacyArtifact,
+	uris []*ss.ShadowsocksURI, configs []*ss.ShadowsocksClientConfig) error {
+	for _, a := range artifacts {
+		if !a.enabled {
+			continue
+		}
+
+		write := a.write
+		err := writePerServer(*opts.outputDir, *opts.nameTemplate, a.suffix, uris,
+			func(w io.Writer, i int, uri *ss.ShadowsocksURI) error {
+				return write(w, uris[i:i+1], configs[i:i+1])
+			})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+// pipelineFlag ... Collects -filter, -sort and -rename stages in command line order.
+type pipelineFlag struct {
+	pipeline *ss.Pipeline
+	parse    func(value string) (ss.Stage, error)
+}
+
+// String ... Implements flag.Value.
+func (f *pipelineFlag) String() string {
+	return ""
+}
+
+// Set ... Implements flag.Value.
+func (f *pipelineFlag) Set(value string) error {
+	stage, err := f.parse(value)
+	if err != nil {
+		return err
+	}
+
+	*f.pipeline = append(*f.pipeline, stage)
+
+	return nil
+}
+
+// addPipelineFlags ... Register -filter, -sort and -rename appending to pipeline.
+func addPipelineFlags(fs *flag.FlagSet, pipeline *ss.Pipeline) {
+	fs.Var(&pipelineFlag{pipeline, parseFilterStage}, "filter",
+		"keep servers matching <field>=<value> or <field>!=<value>, fields: tag, host, port, method, plugin (repeatable)")
+	fs.Var(&pipelineFlag{pipeline, parseSortStage}, "sort",
+		"sort servers by comma separated fields, \"-\" prefix for descending: tag, host, port, method, password, plugin")
+	fs.Var(&pipelineFlag{pipeline, parseRenameStage}, "rename",
+		"rename tags with a template, e.g. \"{{.Country}}-{{.Index}}\"")
+}
+
+// parseFilterStage ... Parse -filter value.
+func parseFilterStage(value string) (ss.Stage, error) {
+	filter, err := ss.ParseFilter(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.FilterStage(filter), nil
+}
+
+// parseSortStage ... Parse -sort value.
+func parseSortStage(value string) (ss.Stage, error) {
+	fields := strings.Split(value, ",")
+
+	// Validate fields early.
+	if err := ss.SortBy(nil, fields...); err != nil {
+		return nil, err
+	}
+
+	return ss.SortStage(fields...), nil
+}
+
+// parseRenameStage ... Parse -rename value.
+func parseRenameStage(value string) (ss.Stage, error) {
+	// Validate template early, including its fields.
+	if err := ss.CheckTemplate(value); err != nil {
+		return nil, err
+	}
+
+	return ss.RenameStage(value), nil
+}
+
+// processServers ... Deduplicate servers and run pipeline over them.
+// Collapsed servers are reported to stderr by their input lines, see uriLines,
+// or by position if lines is nil.
+func processServers(uris []*ss.ShadowsocksURI, lines []int, dedupe bool, policyName string, pipeline ss.Pipeline) ([]*ss.ShadowsocksURI, error) {
+	policy, err := ss.ParseTagPolicy(policyName)
+	if err != nil {
+		return nil, err
+	}
+
+	if dedupe {
+		result := ss.Merge(uris, policy)
+
+		for _, g := range result.Collapsed() {
+			if lines != nil {
+				fmt.Fprintf(os.Stderr, "Merged lines %v into %s\n", lineNumbers(g.Indices, lines), g.Merged.Remote.String())
+			} else {
+				fmt.Fprintf(os.Stderr, "Merged servers %v into %s\n", lineNumbers(g.Indices, nil), g.Merged.Remote.String())
+			}
+		}
+
+		uris = result.URIs
+	}
+
+	return pipeline.Run(uris)
+}
+
+// addDecodeOptionFlags ... Register -strict-plugins, returns options set by it once parsed.
+func addDecodeOptionFlags(fs *flag.FlagSet) *ss.DecodeOptions {
+	opts := &ss.DecodeOptions{}
+	fs.BoolVar(&opts.StrictPlugins, "strict-plugins", false, "reject servers with options their plugin does not accept")
+
+	return opts
+}
+
+// addRedactFlag ... Register -redact.
+func addRedactFlag(fs *flag.FlagSet) *string {
+	return fs.String("redact", "none", "mask passwords and plugin secrets in output, or strip them from URIs: none, partial or full")
+}
+
+// uriFormats ... Output formats made of URIs, which secrets are stripped from.
+var uriFormats = map[string]bool{"sip002": true, "legacy": true, "plain": true, "subscription": true}
+
+// redactServers ... Returns copies of servers with secrets masked by the named
+// mode, or stripped if they are written as URIs.
+func redactServers(uris []*ss.ShadowsocksURI, modeName string, asURIs bool) ([]*ss.ShadowsocksURI, error) {
+	mode, err := ss.ParseRedactMode(modeName)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted := make([]*ss.ShadowsocksURI, len(uris))
+
+	for i, uri := range uris {
+		redacted[i] = redactServer(uri, mode, asURIs)
+	}
+
+	return redacted, nil
+}
+
+// redactServer ... Returns copy of server with secrets masked by mode, or
+// stripped if it is written as a URI.
+func redactServer(uri *ss.ShadowsocksURI, mode ss.RedactMode, asURI bool) *ss.ShadowsocksURI {
+	if asURI && mode != ss.RedactNone {
+		return uri.StripSecrets()
+	}
+
+	return uri.Redact(mode)
+}
+
+// encodeURI ... Encode shadowsocks URI.
+func encodeURI(uri *ss.ShadowsocksURI, legacy bool) string {
+	if legacy {
+		return uri.EncodeBase64URI()
+	}
+
+	return uri.EncodeSIP002URI()
+}
+
+// uriLines ... Returns the input line number of each URI in data, a list of
+// URIs one per line starting at firstLine, with blank lines skipped like
+// ss.DecodeURIList does. Returns nil for a base64 subscription, whose lines
+// are not those of the input.
+func uriLines(data string, firstLine int) []int {
+	if !strings.Contains(data, "://") {
+		return nil
+	}
+
+	lines := []int{}
+
+	for i, line := range strings.Split(data, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, firstLine+i)
+		}
+	}
+
+	return lines
+}
+
+// lineNumbers ... Convert input indices to their line numbers in lines, or
+// to 1-based positions if lines is nil.
+func lineNumbers(indices []int, lines []int) []int {
+	numbers := make([]int, len(indices))
+
+	for i, index := range indices {
+		if lines != nil && index < len(lines) {
+			numbers[i] = lines[index]
+		} else {
+			numbers[i] = index + 1
+		}
+	}
+
+	return numbers
+}
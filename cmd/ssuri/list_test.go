@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLineNumbers(t *testing.T) {
+	tests := []struct {
+		input    string // Read like readInputFile
+		indices  []int
+		expected []int
+	}{
+		{"ss://a\nss://b\nss://c\n", []int{0, 2}, []int{1, 3}},
+		{"ss://a\n\n  \nss://b\n", []int{0, 1}, []int{1, 4}},
+		{"\n\n\nss://a\n\nss://b\nss://c", []int{1, 2}, []int{6, 7}},
+		{"  \n\t\nss://a\nss://b", []int{0, 1}, []int{3, 4}},
+		{"ss://a\r\n\r\nss://b\r\n", []int{1}, []int{3}},
+		{"\nc3M6Ly9h\nc3M6Ly9i\n", []int{0, 1}, []int{1, 2}},
+		{"", []int{0}, []int{1}},
+	}
+
+	for i, ut := range tests {
+		if numbers := lineNumbers(ut.indices, uriLines(trimInput(ut.input))); !reflect.DeepEqual(numbers, ut.expected) {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v", i, ut.expected, numbers)
+		}
+	}
+}
+
+func TestTrimInput(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		firstLine int
+	}{
+		{"ss://a", "ss://a", 1},
+		{"  ss://a \n", "ss://a", 1},
+		{"\n\nss://a\nss://b\n\n", "ss://a\nss://b", 3},
+		{"\r\n \t\r\n  ss://a", "ss://a", 3},
+		{"\n\n", "", 3},
+		{"", "", 1},
+	}
+
+	for i, ut := range tests {
+		if data, firstLine := trimInput(ut.input); data != ut.expected || firstLine != ut.firstLine {
+			t.Errorf("#%d test failed. Expected: %q at %d, Got: %q at %d", i, ut.expected, ut.firstLine, data, firstLine)
+		}
+	}
+}
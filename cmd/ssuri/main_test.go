@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCommand ... Run ssuri with args reading stdin, returns exit code, stdout and stderr.
+func runCommand(t *testing.T, args []string, stdin string) (int, string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := make([]*os.File, 3)
+
+	for i, name := range []string{"stdin", "stdout", "stderr"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		files[i] = f
+	}
+
+	files[0].WriteString(stdin)
+	files[0].Seek(0, 0)
+
+	oldStdin, oldStdout, oldStderr := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = files[0], files[1], files[2]
+
+	defer func() {
+		os.Stdin, os.Stdout, os.Stderr = oldStdin, oldStdout, oldStderr
+	}()
+
+	code := run(args)
+
+	stdout, _ := ioutil.ReadFile(files[1].Name())
+	stderr, _ := ioutil.ReadFile(files[2].Name())
+
+	return code, string(stdout), string(stderr)
+}
+
+func TestRun(t *testing.T) {
+	const (
+		uri     = "ss://YWVzLTI1Ni1nY206Y29ycmVjdCBob3JzZSBiYXR0ZXJ5@192.168.100.1:8888#HK-01"
+		good    = "ss://YWVzLTI1Ni1nY206Y29ycmVjdCBob3JzZSBiYXR0ZXJ5@example.com:8388#good"
+		weak    = "ss://cmM0LW1kNTp0ZXN0@example.com:8388#weak"
+		invalid = "ss://cmM0LW1kNTpwYXNzd2Q=@192.168.100.1:8888/?plugin=v2ray-plugin%3Bmode%3Dgrpc#b"
+		config  = `{"server": "192.168.100.1", "server_port": 8888, "method": "aes-256-gcm", "password": "test"}`
+	)
+
+	subscription := base64.StdEncoding.EncodeToString([]byte(uri + "\n" + weak + "\n"))
+
+	tests := []struct {
+		args   []string
+		stdin  string
+		code   int
+		stdout string // Expected in stdout unless empty
+		stderr string // Expected in stderr unless empty
+	}{
+		// Dispatch and help.
+		{[]string{"frobnicate"}, "", exitUsage, "", `unknown command "frobnicate"`},
+		{[]string{"help"}, "", exitOK, "", "Commands:"},
+		{[]string{"help", "decode"}, "", exitOK, "", "Usage: "},
+		{[]string{"help", "frobnicate"}, "", exitUsage, "", "unknown command"},
+		{[]string{"decode", "-h"}, "", exitOK, "", "-format"},
+		{[]string{"decode", "-no-such-flag"}, "", exitUsage, "", "-no-such-flag"},
+		{[]string{"decode", "extra"}, "", exitUsage, "", `unexpected argument "extra"`},
+
+		// decode
+		{[]string{"decode"}, uri, exitOK, "192.168.100.1", ""},
+		{[]string{"decode", "-format", "json"}, subscription, exitOK, `"example.com"`, ""},
+		{[]string{"decode", "-redact", "full"}, uri, exitOK, "HK-01", ""},
+		{[]string{"decode"}, "ss://bad", exitError, "", "invalid"},
+		{[]string{"decode", "-strict-plugins"}, invalid, exitError, "", "server #1: "},
+
+		// encode
+		{[]string{"encode"}, config, exitOK, "ss://YWVzLTI1Ni1nY206dGVzdA==@192.168.100.1:8888", ""},
+		{[]string{"encode"}, uri, exitError, "", ""},
+		{[]string{"encode", "-interval", "5s"}, config, exitUsage, "", "-interval needs -watch"},
+
+		// convert
+		{[]string{"convert", "-to", "sip008"}, uri, exitOK, `"servers"`, ""},
+		{[]string{"convert", "-from", "uri", "-to", "plain"}, uri, exitOK, "ss://aes-256-gcm:", ""},
+		{[]string{"convert", "-to", "bogus"}, uri, exitUsage, "", "invalid output format"},
+		{[]string{"convert", "-from", "bogus"}, uri, exitUsage, "", "invalid input format"},
+		{[]string{"convert", "-to", "sip002"}, invalid, exitOK, "mode%3Dgrpc", ""},
+		{[]string{"convert", "-to", "sip002", "-strict-plugins"}, invalid, exitError, "", "server #1: "},
+
+		// lint
+		{[]string{"lint"}, good, exitOK, "", ""},
+		{[]string{"lint", "-fail-on", "error"}, uri, exitOK, "private or local address", ""},
+		{[]string{"lint"}, weak, exitError, "weak", ""},
+		{[]string{"lint", "-fail-on", "error", "-level", "error"}, uri + "\n" + weak, exitError, "cipher", ""},
+		{[]string{"lint", "-format", "json"}, weak, exitError, `"check": "cipher"`, ""},
+		{[]string{"lint", "-level", "error", "-fail-on", "error"}, subscription, exitError, "#2 ", ""},
+		{[]string{"lint"}, "\n\nss://bad\n" + good, exitError, "", "line 3: "},
+		{[]string{"lint", "-level", "bogus"}, uri, exitUsage, "", ""},
+		{[]string{"lint", "-format", "bogus"}, uri, exitUsage, "", "invalid output format"},
+
+		// sub
+		{[]string{"sub", "-dedupe"}, uri + "\n\n" + uri, exitOK, uri, "Merged lines [1 3]"},
+		{[]string{"sub", "-filter", "tag=^HK"}, subscription, exitOK, "#HK-01", ""},
+		{[]string{"sub", "-b64"}, uri, exitOK, base64.StdEncoding.EncodeToString([]byte(uri + "\n")), ""},
+		{[]string{"sub", "-rename", "{{.Tga}}"}, uri, exitUsage, "", "can't evaluate field Tga"},
+		{[]string{"sub", "-tag-policy", "bogus", "-dedupe"}, uri, exitError, "", ""},
+
+		// set
+		{[]string{"set", "port=8443"}, uri, exitOK, "@192.168.100.1:8443#HK-01", ""},
+		{[]string{"set", "-filter", "tag=^HK", "tag=JP-01"}, uri + "\n" + weak, exitOK, "#JP-01", ""},
+		{[]string{"set", uri, "method=chacha20-ietf-poly1305"}, "", exitOK, "@192.168.100.1:8888#HK-01", ""},
+		{[]string{"set", "password="}, uri, exitUsage, "", "empty password"},
+		{[]string{"set"}, uri, exitUsage, "", "no edit given"},
+		{[]string{"set", "port=http"}, uri, exitUsage, "", "invalid port"},
+
+		// pack and unpack need a passphrase.
+		{[]string{"pack"}, uri, exitError, "", "no passphrase"},
+		{[]string{"unpack"}, "bundle", exitError, "", "no passphrase"},
+	}
+
+	t.Setenv(passphraseEnv, "")
+
+	for i, ut := range tests {
+		code, stdout, stderr := runCommand(t, ut.args, ut.stdin)
+
+		if code != ut.code || !strings.Contains(stdout, ut.stdout) || !strings.Contains(stderr, ut.stderr) {
+			t.Errorf("#%d test failed. %q: Expected: %d, %q, %q, Got: %d\nstdout: %q\nstderr: %q",
+				i, ut.args, ut.code, ut.stdout, ut.stderr, code, stdout, stderr)
+		}
+	}
+}
+
+func TestRunPackUnpack(t *testing.T) {
+	const uris = "ss://YWVzLTI1Ni1nY206dGVzdA==@192.168.100.1:8888#a\n" +
+		"ss://YWVzLTI1Ni1nY206dGVzdA==@192.168.100.2:8888#b\n"
+
+	t.Setenv(passphraseEnv, "correct horse battery")
+
+	code, bundle, stderr := runCommand(t, []string{"pack"}, uris)
+	if code != exitOK || bundle == "" {
+		t.Fatalf("Expected bundle, Got: %d, %q", code, stderr)
+	}
+
+	code, unpacked, stderr := runCommand(t, []string{"unpack"}, bundle)
+	if code != exitOK || unpacked != uris {
+		t.Errorf("Expected: %q, Got: %d, %q, %q", uris, code, unpacked, stderr)
+	}
+
+	t.Setenv(passphraseEnv, "wrong horse battery")
+
+	if code, _, _ := runCommand(t, []string{"unpack"}, bundle); code != exitError {
+		t.Errorf("Expected failure for wrong passphrase, Got: %d", code)
+	}
+
+	// Outputs go to -o as well.
+	output := filepath.Join(t.TempDir(), "out.txt")
+	t.Setenv(passphraseEnv, "correct horse battery")
+
+	if code, _, _ := runCommand(t, []string{"unpack", "-o", output}, bundle); code != exitOK {
+		t.Errorf("Expected success, Got: %d", code)
+	}
+
+	if data, err := ioutil.ReadFile(output); err != nil || string(data) != uris {
+		t.Errorf("Expected: %q in %s, Got: %q, %v", uris, output, data, err)
+	}
+}
+
+func TestRunWriteError(t *testing.T) {
+	const uri = "ss://YWVzLTI1Ni1nY206dGVzdA==@192.168.100.1:8888#a"
+
+	// Every write to /dev/full fails with ENOSPC.
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full")
+	}
+
+	t.Setenv(passphraseEnv, "correct horse battery")
+
+	_, bundle, _ := runCommand(t, []string{"pack"}, uri)
+
+	tests := []struct {
+		args  []string
+		stdin string
+	}{
+		{[]string{"decode"}, uri},
+		{[]string{"convert", "-to", "sip008"}, uri},
+		{[]string{"sub"}, uri},
+		{[]string{"set", "port=8443"}, uri},
+		{[]string{"lint"}, uri},
+		{[]string{"lint", "-format", "json"}, uri},
+		{[]string{"pack"}, uri},
+		{[]string{"unpack"}, bundle},
+	}
+
+	for i, ut := range tests {
+		// Flags come before the arguments of set.
+		args := append([]string{ut.args[0], "-o", "/dev/full"}, ut.args[1:]...)
+
+		if code, _, stderr := runCommand(t, args, ut.stdin); code != exitError || stderr == "" {
+			t.Errorf("#%d test failed. %q: Expected write error, Got: %d, %q", i, args, code, stderr)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+// clientOptionFlags ... Flags overriding client settings of generated JSON configurations.
+type clientOptionFlags struct {
+	fs           *flag.FlagSet
+	defaultsFile *string
+	localAddress *string
+	localPort    *int
+	timeout      *int
+	fastOpen     *bool
+	workers      *int
+	mode         *string
+	nameserver   *string
+}
+
+// addClientOptionFlags ... Register -defaults and the client setting flags.
+func addClientOptionFlags(fs *flag.FlagSet) *clientOptionFlags {
+	defaults := ss.DefaultClientOptions()
+
+	return &clientOptionFlags{
+		fs:           fs,
+		defaultsFile: fs.String("defaults", "", "JSON file of client settings, e.g. {\"local_port\": 1081}"),
+		localAddress: fs.String("local-address", defaults.Local.Hostname(), "local listen address"),
+		localPort:    fs.Int("local-port", defaults.Local.Port(), "local listen port"),
+		timeout:      fs.Int("timeout", defaults.Timeout, "connection timeout in seconds"),
+		fastOpen:     fs.Bool("fast-open", defaults.FastOpen, "enable TCP fast open"),
+		workers:      fs.Int("workers", defaults.Workers, "number of workers"),
+		mode:         fs.String("mode", defaults.Mode, "tcp_only, udp_only or tcp_and_udp"),
+		nameserver:   fs.String("nameserver", defaults.Nameserver, "DNS server"),
+	}
+}
+
+// resolve ... Returns client options. Explicit flags win over -defaults,
+// which wins over the built-in defaults.
+func (f *clientOptionFlags) resolve() (*ss.ClientOptions, error) {
+	opts := ss.DefaultClientOptions()
+
+	if *f.defaultsFile != "" {
+		data, err := ioutil.ReadFile(*f.defaultsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		opts, err = ss.LoadClientOptions(data, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "local-address":
+			opts.Local = ss.NewServer(*f.localAddress, opts.Local.Port())
+		case "local-port":
+			opts.Local = ss.NewServer(opts.Local.Hostname(), *f.localPort)
+		case "timeout":
+			opts.Timeout = *f.timeout
+		case "fast-open":
+			opts.FastOpen = *f.fastOpen
+		case "workers":
+			opts.Workers = *f.workers
+		case "mode":
+			opts.Mode = *f.mode
+		case "nameserver":
+			opts.Nameserver = *f.nameserver
+		}
+	})
+
+	return opts, opts.Validate()
+}
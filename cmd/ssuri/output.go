@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+// defaultNameTemplate ... Default template of per-server file names.
+const defaultNameTemplate = "{{.Index}}-{{.Tag}}"
+
+// outputFiles ... Output files opened by name, so that artifacts sharing a
+// destination are appended to the same file instead of truncating each other.
+// Files are buffered until commit replaces them at once.
+type outputFiles struct {
+	names   []string // In order of opening
+	buffers map[string]*bytes.Buffer
+}
+
+// open ... Open output file by name, "-" for stdout.
+func (o *outputFiles) open(name string) io.Writer {
+	if name == "-" {
+		return os.Stdout
+	}
+
+	if o.buffers == nil {
+		o.buffers = make(map[string]*bytes.Buffer)
+	}
+
+	if b, ok := o.buffers[name]; ok {
+		return b
+	}
+
+	b := &bytes.Buffer{}
+	o.buffers[name] = b
+	o.names = append(o.names, name)
+
+	return b
+}
+
+// commit ... Write all opened files, see writeFileAtomic.
+func (o *outputFiles) commit() error {
+	for _, name := range o.names {
+		if err := writeFileAtomic(name, o.buffers[name].Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeOutput ... Write data to the output file name, "-" for stdout, see writeFileAtomic.
+func writeOutput(name string, data []byte) error {
+	if name == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	return writeFileAtomic(name, data)
+}
+
+// perServerNames ... Expand file name template once per server.
+// Path separators and other characters unsafe in file names are replaced by "_".
+func perServerNames(uris []*ss.ShadowsocksURI, nameTemplate string) ([]string, error) {
+	names, err := ss.ExecuteTemplate(uris, nameTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+
+	for i, name := range names {
+		name = strings.Map(func(r rune) rune {
+			if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+				return '_'
+			}
+
+			return r
+		}, strings.TrimSpace(name))
+
+		if name == "" || name == "." || name == ".." {
+			return nil, fmt.Errorf("empty file name for server #%d", i+1)
+		}
+
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate file name %q, add {{.Index}} to the name template", name)
+		}
+
+		seen[name] = true
+		names[i] = name
+	}
+
+	return names, nil
+}
+
+// writePerServer ... Write one file per server into dir, named by template plus suffix.
+func writePerServer(dir, nameTemplate, suffix string, uris []*ss.ShadowsocksURI,
+	write func(w io.Writer, i int, uri *ss.ShadowsocksURI) error) error {
+	names, err := perServerNames(uris, nameTemplate)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for i, uri := range uris {
+		var b bytes.Buffer
+
+		if err := write(&b, i, uri); err != nil {
+			return err
+		}
+
+		if err := writeFileAtomic(filepath.Join(dir, names[i]+suffix), b.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// outputDirFlags ... Flags writing one file per server instead of -o.
+type outputDirFlags struct {
+	dir          *string
+	nameTemplate *string
+}
+
+// addOutputDirFlags ... Register -output-dir and -name-template.
+func addOutputDirFlags(fs *flag.FlagSet) *outputDirFlags {
+	return &outputDirFlags{
+		dir:          fs.String("output-dir", "", "write one file per server into this directory instead of -o"),
+		nameTemplate: fs.String("name-template", defaultNameTemplate, "template of file names in -output-dir, see -rename of sub"),
+	}
+}
+
+// write ... Write servers to -o, or one file per server with suffix to -output-dir.
+func (f *outputDirFlags) write(files *ioFlags, suffix string, uris []*ss.ShadowsocksURI,
+	write func(w io.Writer, i int, uri *ss.ShadowsocksURI) error) error {
+	if *f.dir != "" {
+		return writePerServer(*f.dir, *f.nameTemplate, suffix, uris, write)
+	}
+
+	var b bytes.Buffer
+
+	for i, uri := range uris {
+		if err := write(&b, i, uri); err != nil {
+			return err
+		}
+	}
+
+	return writeOutput(*files.output, b.Bytes())
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+// newTestServers ... Returns servers with tags.
+func newTestServers(tags ...string) []*ss.ShadowsocksURI {
+	uris := []*ss.ShadowsocksURI{}
+
+	for i, tag := range tags {
+		uris = append(uris, &ss.ShadowsocksURI{
+			Remote: ss.NewServer("192.168.100.1", 8388+i),
+			Auth:   ss.NewAuthInfo("aes-256-gcm", "test"),
+			Tag:    tag,
+		})
+	}
+
+	return uris
+}
+
+func TestPerServerNames(t *testing.T) {
+	tests := []struct {
+		tags     []string
+		template string
+		expected []string // nil if an error is expected
+	}{
+		{[]string{"HK", "JP"}, defaultNameTemplate, []string{"1-HK", "2-JP"}},
+		{[]string{"HK", "JP"}, "{{.Tag}}", []string{"HK", "JP"}},
+		{[]string{"a/b", `c\d`, "e:f*?", "<g>|\"h\""}, "{{.Tag}}", []string{"a_b", "c_d", "e_f__", "_g___h_"}},
+		{[]string{"../etc", "tab\there"}, "{{.Tag}}", []string{".._etc", "tab_here"}},
+		{[]string{"  padded  "}, "{{.Tag}}", []string{"padded"}},
+		{[]string{"", "JP"}, defaultNameTemplate, []string{"1-", "2-JP"}},
+		{[]string{"HK", "HK"}, defaultNameTemplate, []string{"1-HK", "2-HK"}},
+		{[]string{"HK", "HK"}, "{{.Tag}}", nil},
+		{[]string{"a/b", "a:b"}, "{{.Tag}}", nil},
+		{[]string{"", "JP"}, "{{.Tag}}", nil},
+		{[]string{"   "}, "{{.Tag}}", nil},
+		{[]string{"."}, "{{.Tag}}", nil},
+		{[]string{".."}, "{{.Tag}}", nil},
+		{[]string{"HK"}, "{{.Missing}}", nil},
+		{[]string{"HK"}, "{{", nil},
+	}
+
+	for i, ut := range tests {
+		names, err := perServerNames(newTestServers(ut.tags...), ut.template)
+
+		if ut.expected == nil {
+			if err == nil {
+				t.Errorf("#%d test failed. Expected error, Got: %q", i, names)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(names, ut.expected) {
+			t.Errorf("#%d test failed. Expected: %q, Got: %q, %v", i, ut.expected, names, err)
+		}
+	}
+}
+
+// runLegacyOutputs ... Run the legacy interface with args on data, like runLegacy without -watch.
+func runLegacyOutputs(t *testing.T, args []string, data string) error {
+	t.Helper()
+
+	opts := &legacyOptions{}
+	if err := newLegacyFlagSet(opts).Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	clientOpts, err := opts.clientOptions.resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = writeLegacyOutputs(opts, clientOpts, ss.RedactNone, data, 1)
+
+	return err
+}
+
+// readOutput ... Returns content of the file name in dir, "" if missing.
+func readOutput(dir, name string) string {
+	data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+	return string(data)
+}
+
+func TestLegacyArtifactDestinations(t *testing.T) {
+	const uri = "ss://YWVzLTI1Ni1nY206dGVzdA==@192.168.100.1:8888#example-server"
+
+	tests := []struct {
+		args     []string            // Relative file names are made absolute in a temporary directory
+		expected map[string][]string // Output file to what it must contain
+		missing  []string            // Output files that must not exist
+	}{
+		{
+			[]string{"-generate-json-config", "-json-o", "config.json", "-generate-qr", "-qr-o", "qr.txt", "-generate-uri", "-o", "out.txt"},
+			map[string][]string{
+				"config.json": {`"server": "192.168.100.1"`, `"server_port": 8888`},
+				"qr.txt":      {"\x1b[47m"},
+				"out.txt":     {"ss://"},
+			},
+			nil,
+		},
+		{
+			[]string{"-generate-json-config", "-generate-uri", "-o", "out.txt"},
+			map[string][]string{"out.txt": {`"server_port": 8888`, "ss://"}},
+			[]string{"config.json"},
+		},
+		{
+			[]string{"-dump-uri", "-dump-o", "all.txt", "-generate-json-config", "-json-o", "all.txt", "-o", "out.txt"},
+			map[string][]string{"all.txt": {"example-server", `"server_port": 8888`}},
+			[]string{"out.txt"},
+		},
+		{
+			[]string{"-generate-uri", "-uri-o", "uri.txt", "-generate-qr", "-o", "out.txt"},
+			map[string][]string{"uri.txt": {"ss://"}, "out.txt": {"\x1b[47m"}},
+			nil,
+		},
+	}
+
+	for i, ut := range tests {
+		dir := t.TempDir()
+
+		args := append([]string{}, ut.args...)
+		for j := 1; j < len(args); j++ {
+			if strings.HasSuffix(args[j-1], "-o") {
+				args[j] = filepath.Join(dir, args[j])
+			}
+		}
+
+		if err := runLegacyOutputs(t, args, uri); err != nil {
+			t.Fatalf("#%d test failed. %v", i, err)
+		}
+
+		for name, contents := range ut.expected {
+			output := readOutput(dir, name)
+
+			for _, s := range contents {
+				if !strings.Contains(output, s) {
+					t.Errorf("#%d test failed. Expected %q in %s, Got: %q", i, s, name, output)
+				}
+			}
+		}
+
+		for _, name := range ut.missing {
+			if output := readOutput(dir, name); output != "" {
+				t.Errorf("#%d test failed. Expected no %s, Got: %q", i, name, output)
+			}
+		}
+	}
+}
+
+func TestLegacyArtifactsPerServer(t *testing.T) {
+	const uris = "ss://YWVzLTI1Ni1nY206dGVzdA==@192.168.100.1:8888#HK/01\n" +
+		"ss://YWVzLTI1Ni1nY206dGVzdA==@192.168.100.2:8888#JP 01\n"
+
+	dir := t.TempDir()
+	args := []string{"-generate-json-config", "-generate-uri", "-output-dir", dir, "-name-template", "{{.Tag}}"}
+
+	if err := runLegacyOutputs(t, args, uris); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"HK_01.json", `"server": "192.168.100.1"`},
+		{"HK_01.uri", "@192.168.100.1:8888"},
+		{"JP 01.json", `"server": "192.168.100.2"`},
+		{"JP 01.uri", "@192.168.100.2:8888"},
+	}
+
+	for i, ut := range tests {
+		if output := readOutput(dir, ut.name); !strings.Contains(output, ut.expected) {
+			t.Errorf("#%d test failed. Expected %q in %s, Got: %q", i, ut.expected, ut.name, output)
+		}
+	}
+
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != len(tests) {
+		t.Errorf("Expected %d files in %s, Got: %d", len(tests), dir, len(entries))
+	}
+
+	// Servers with the same file name are rejected before anything is written.
+	dir = t.TempDir()
+	args = []string{"-generate-uri", "-output-dir", dir, "-name-template", "same"}
+
+	if err := runLegacyOutputs(t, args, uris); err == nil {
+		t.Errorf("Expected error for duplicate file names")
+	}
+
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no files in %s, Got: %d", dir, len(entries))
+	}
+}
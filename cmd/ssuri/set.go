@@ -0,0 +1,279 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+var setCommand = &command{
+	name:    "set",
+	args:    "[-i in_file] [-o out_file] [-from format] [-filter spec] [uri...] field=value...",
+	summary: "Edit fields of servers, e.g. rotate a password or move a port, keeping the format of the input.",
+	run:     runSet,
+}
+
+// filtersFlag ... Collects repeated -filter values.
+type filtersFlag []ss.Filter
+
+// String ... Implements flag.Value.
+func (f *filtersFlag) String() string {
+	return ""
+}
+
+// Set ... Implements flag.Value.
+func (f *filtersFlag) Set(value string) error {
+	filter, err := ss.ParseFilter(value)
+	if err != nil {
+		return err
+	}
+
+	*f = append(*f, filter)
+
+	return nil
+}
+
+// runSet ... ssuri set
+func runSet(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	from := fs.String("from", "uri", "input format, written back the same way: "+strings.Join(ss.DecoderFormats(), ", "))
+
+	var filters filtersFlag
+	fs.Var(&filters, "filter", "only edit servers matching <field>=<value> or <field>!=<value>, fields: tag, host, port, method, plugin (repeatable)")
+
+	if code, ok := parseFlagsAndArgs(fs, args, len(args)); !ok {
+		return code
+	}
+
+	if !checkDecoderFormat(*from) || *from != "uri" && !checkEncoderFormat(*from) {
+		return exitUsage
+	}
+
+	// URIs given as arguments replace -i.
+	var lines, specs []string
+
+	for _, arg := range fs.Args() {
+		if strings.HasPrefix(arg, "ss://") {
+			lines = append(lines, arg)
+		} else {
+			specs = append(specs, arg)
+		}
+	}
+
+	if len(specs) == 0 {
+		fmt.Fprintf(fs.Output(), "no edit given\n")
+		fs.Usage()
+
+		return exitUsage
+	}
+
+	edits := make([]ss.Edit, len(specs))
+
+	for i, spec := range specs {
+		edit, err := ss.ParseEdit(spec)
+		if err != nil {
+			fmt.Fprintf(fs.Output(), "%v\n", err)
+			return exitUsage
+		}
+
+		edits[i] = edit
+	}
+
+	data := strings.Join(lines, "\n")
+
+	if len(lines) == 0 {
+		var err error
+
+		if data, err = files.readInput(); err != nil {
+			return fail(err)
+		}
+	}
+
+	servers, err := decodeForEdit(data, *from)
+	if err != nil {
+		return fail(err)
+	}
+
+	edited := 0
+
+	for i, uri := range servers.list() {
+		if !matchFilters(uri, filters) {
+			continue
+		}
+
+		if err := servers.edit(i, edits); err != nil {
+			return fail(fmt.Errorf("server %d: %v", i+1, err))
+		}
+
+		edited++
+	}
+
+	if edited == 0 {
+		return fail(errors.New("no server matches -filter"))
+	}
+
+	encoded, err := servers.encode()
+	if err != nil {
+		return fail(err)
+	}
+
+	outputFile, closeOutput, err := files.openOutput()
+	if err != nil {
+		return fail(err)
+	}
+	defer closeOutput()
+
+	if _, err := outputFile.Write(encoded); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
+
+// matchFilters ... Reports whether uri matches all filters.
+func matchFilters(uri *ss.ShadowsocksURI, filters []ss.Filter) bool {
+	for _, f := range filters {
+		if !f(uri) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// editableServers ... Servers read by set, edited and written back in the
+// format they were read in.
+type editableServers interface {
+	list() []*ss.ShadowsocksURI        // Servers, as filters see them
+	edit(i int, edits []ss.Edit) error // Apply edits to the i-th server
+	encode() ([]byte, error)           // Encode in the input format
+}
+
+// decodeForEdit ... Decode servers in the named format for editing. URI lines
+// keep their flavors and a base64 subscription stays one, while JSON client
+// configurations are edited as such to keep their local settings.
+func decodeForEdit(data, from string) (editableServers, error) {
+	switch {
+	case from == "client-json":
+		configs, err := ss.DecodeClientJSONList([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+
+		return &clientJSONServers{configs, ss.IsClientJSONArray([]byte(data))}, nil
+	case from == "uri" && strings.Contains(data, "://"):
+		return decodeURILines(data)
+	case from == "uri":
+		from = "subscription"
+	}
+
+	uris, err := ss.DecodeFormat([]byte(data), from)
+	if err != nil {
+		return nil, err
+	}
+
+	return &uriServers{uris: uris, format: from}, nil
+}
+
+// uriServers ... Servers of a URI based format, or URI lines of mixed flavors.
+type uriServers struct {
+	uris    []*ss.ShadowsocksURI
+	flavors []string // Flavor of each URI line, nil if encoded by format
+	format  string
+}
+
+// decodeURILines ... Decode one URI per line, remembering their flavors.
+func decodeURILines(data string) (*uriServers, error) {
+	servers := &uriServers{uris: []*ss.ShadowsocksURI{}, flavors: []string{}}
+
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		uri, err := ss.DecodeURI(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+
+		servers.uris = append(servers.uris, uri)
+		servers.flavors = append(servers.flavors, ss.DetectURIFlavor(line))
+	}
+
+	return servers, nil
+}
+
+// list ... Implements editableServers.
+func (s *uriServers) list() []*ss.ShadowsocksURI {
+	return s.uris
+}
+
+// edit ... Implements editableServers. Servers no longer fitting their
+// flavor are reported, they will be written as SIP002.
+func (s *uriServers) edit(i int, edits []ss.Edit) error {
+	uri, err := ss.ApplyEdits(s.uris[i], edits...)
+	if err != nil {
+		return err
+	}
+
+	if s.flavors != nil && !uri.FitsFlavor(s.flavors[i]) {
+		fmt.Fprintf(os.Stderr, "server %d: %s URIs cannot hold a plugin, writing SIP002\n", i+1, s.flavors[i])
+	}
+
+	s.uris[i] = uri
+
+	return nil
+}
+
+// encode ... Implements editableServers.
+func (s *uriServers) encode() ([]byte, error) {
+	if s.flavors == nil {
+		return ss.EncodeFormat(s.uris, s.format)
+	}
+
+	var b strings.Builder
+
+	for i, uri := range s.uris {
+		b.WriteString(uri.EncodeFlavor(s.flavors[i]) + "\n")
+	}
+
+	return []byte(b.String()), nil
+}
+
+// clientJSONServers ... JSON client configurations, edited with all settings.
+type clientJSONServers struct {
+	configs []*ss.ShadowsocksClientConfig
+	array   bool
+}
+
+// list ... Implements editableServers.
+func (s *clientJSONServers) list() []*ss.ShadowsocksURI {
+	uris := make([]*ss.ShadowsocksURI, len(s.configs))
+	for i, scc := range s.configs {
+		uris[i] = ss.ToShadowsocksURI(scc)
+	}
+
+	return uris
+}
+
+// edit ... Implements editableServers.
+func (s *clientJSONServers) edit(i int, edits []ss.Edit) error {
+	scc, err := ss.ApplyClientEdits(s.configs[i], edits...)
+	if err != nil {
+		return err
+	}
+
+	s.configs[i] = scc
+
+	return nil
+}
+
+// encode ... Implements editableServers.
+func (s *clientJSONServers) encode() ([]byte, error) {
+	return ss.EncodeClientJSONList(s.configs, s.array)
+}
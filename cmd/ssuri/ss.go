@@ -2,15 +2,15 @@ package main
 
 import (
 	"fmt"
-	"os"
+	"io"
 
 	"github.com/mdp/qrterminal"
 	"github.com/vgxbj/ssuri/pkg/ss"
 )
 
-// decodeJSONConfig ... Decode JSON configuration.
-func decodeJSONConfig(data []byte) (*ss.ShadowsocksClientConfig, error) {
-	return ss.DecodeJSON(data)
+// decodeJSONConfig ... Decode JSON configuration, missing fields are taken from opts.
+func decodeJSONConfig(data []byte, opts *ss.ClientOptions) (*ss.ShadowsocksClientConfig, error) {
+	return ss.DecodeJSONWithOptions(data, opts)
 }
 
 // decodeURI ... Decode shadowsocks URI.
@@ -23,8 +23,8 @@ func decodeURI(uri string, legacy bool) (*ss.ShadowsocksURI, error) {
 }
 
 // generateShadowsocksClientConfig ... Generate shadowsocks client configuration.
-func generateShadowsocksClientConfig(uri *ss.ShadowsocksURI) *ss.ShadowsocksClientConfig {
-	return ss.ToShadowsocksClientConfig(uri)
+func generateShadowsocksClientConfig(uri *ss.ShadowsocksURI, opts *ss.ClientOptions) *ss.ShadowsocksClientConfig {
+	return ss.ToShadowsocksClientConfigWithOptions(uri, opts)
 }
 
 // generateShadowsocksURI ... Generate shadowsocks URI scheme.
@@ -32,34 +32,25 @@ func generateShadowsocksURI(scc *ss.ShadowsocksClientConfig) *ss.ShadowsocksURI
 	return ss.ToShadowsocksURI(scc)
 }
 
-// dumpShadowsocksURI ... dump shadowsocks base64 encoded URI.
-func dumpShadowsocksURI(ssu *ss.ShadowsocksURI, outputFile *os.File) {
-	if ssu.Tag != "" {
-		fmt.Fprintf(outputFile, "Server #%s:\n", ssu.Tag)
-	} else {
-		fmt.Fprintf(outputFile, "Server #%s:\n", ssu.Remote.String())
-	}
-
-	fmt.Fprintf(outputFile, "Hostname          : %v\n", ssu.Remote.Hostname())
-	fmt.Fprintf(outputFile, "Port              : %v\n", ssu.Remote.Port())
-	fmt.Fprintf(outputFile, "Encryption Method : %v\n", ssu.Auth.Method())
-	fmt.Fprintf(outputFile, "Password          : %v\n", ssu.Auth.Password())
-	fmt.Fprintf(outputFile, "\n")
+// dumpShadowsocksURI ... dump shadowsocks URIs in the given format.
+func dumpShadowsocksURI(uris []*ss.ShadowsocksURI, format string, outputFile io.Writer) error {
+	return ss.EncodeDump(outputFile, uris, format)
 }
 
 // generateClientJSONConfig ... Generate JSON configuration.
-func generateClientJSONConfig(scc *ss.ShadowsocksClientConfig, outputFile *os.File) {
+func generateClientJSONConfig(scc *ss.ShadowsocksClientConfig, outputFile io.Writer) error {
 	json, err := ss.EncodeClientJSON(scc, false)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return err
 	}
 
-	fmt.Fprintf(outputFile, "%s\n", string(json))
-	fmt.Fprintf(outputFile, "\n")
+	_, err = fmt.Fprintf(outputFile, "%s\n\n", string(json))
+
+	return err
 }
 
 // generateShadowsocksQRCode ... Generate QR code.
-func generateShadowsocksQRCode(ssu *ss.ShadowsocksURI, legacy bool, outputFile *os.File) {
+func generateShadowsocksQRCode(ssu *ss.ShadowsocksURI, legacy bool, outputFile io.Writer) {
 	var uri string
 
 	if legacy {
@@ -0,0 +1,821 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mdp/qrterminal"
+	"github.com/vgxbj/ssuri/pkg/ss"
+	"golang.org/x/term"
+)
+
+var tuiCommand = &command{
+	name:    "tui",
+	args:    "-i in_file [-o out_file] [-flavor flavor] [-copy-cmd command] [-redact mode]",
+	summary: "Browse servers in an interactive terminal UI to filter, copy and edit them, with QR codes.",
+	run:     runTUI,
+}
+
+// tuiHelp ... Key bindings shown by '?'.
+var tuiHelp = []string{
+	"j, down       next server",
+	"k, up         previous server",
+	"space, pgdn   next page",
+	"b, pgup       previous page",
+	"g, G          first, last server",
+	"/             filter by text, or tag=, host=, port=, method=, plugin=",
+	"esc           clear filter",
+	"y             copy SIP002 URI",
+	"e             edit a field of the server",
+	"s             save all servers to -o",
+	"?             toggle this help",
+	"q             quit",
+}
+
+// runTUI ... ssuri tui
+func runTUI(c *command, args []string) int {
+	fs := newFlagSet(c)
+	files := addIOFlags(fs)
+	flavor := fs.String("flavor", "sip002", "format written by save: "+strings.Join(ss.EncoderFormats(), ", "))
+	copyCmd := fs.String("copy-cmd", "", "command reading the copied URI on stdin, e.g. \"xclip -selection clipboard\" (default: terminal clipboard escape)")
+	redact := fs.String("redact", "none", "mask passwords and plugin secrets on screen, strip them from copied URIs and QR codes: none, partial or full")
+
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if !checkEncoderFormat(*flavor) {
+		return exitUsage
+	}
+
+	redactMode, err := ss.ParseRedactMode(*redact)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	// Keys are read from stdin.
+	if *files.input == "-" {
+		fmt.Fprintf(os.Stderr, "tui needs an input file\n")
+		return exitUsage
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Fprintf(os.Stderr, "tui needs a terminal\n")
+		return exitUsage
+	}
+
+	uris, err := readServers(files, false)
+	if err != nil {
+		return fail(err)
+	}
+
+	t := &tui{
+		uris:    uris,
+		output:  *files.output,
+		flavor:  *flavor,
+		copyCmd: strings.Fields(*copyCmd),
+		redact:  redactMode,
+		out:     os.Stdout,
+	}
+
+	t.applyFilter("")
+
+	if err := t.run(os.Stdin); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
+
+// tui ... State of the interactive browser.
+type tui struct {
+	uris     []*ss.ShadowsocksURI
+	visible  []int // Indexes of uris matching filter
+	selected int   // Index into visible
+	top      int   // First row of the list on screen
+	filter   string
+
+	prompt   *tuiPrompt
+	choose   func(key string) // Handles the next key if not nil
+	status   string
+	help     bool
+	modified bool
+	quitting bool
+	done     bool
+
+	output  string
+	flavor  string
+	copyCmd []string
+	redact  ss.RedactMode // Saved servers keep their secrets
+	out     *os.File
+}
+
+// tuiPrompt ... Line being edited on the status line.
+type tuiPrompt struct {
+	label string
+	text  []rune
+	done  func(text string)
+}
+
+// run ... Switch the terminal to raw mode and handle keys until quit.
+func (t *tui) run(in *os.File) error {
+	fd := int(in.Fd())
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	// Alternate screen, hidden cursor.
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+
+	buf := make([]byte, 256)
+
+	for !t.done {
+		t.draw()
+
+		n, err := in.Read(buf)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range parseKeys(buf[:n]) {
+			t.handle(key)
+		}
+	}
+
+	return nil
+}
+
+// csiKeys ... Names of keys sent as escape sequences, by sequence after "ESC [" or "ESC O".
+var csiKeys = map[string]string{
+	"A": "up", "B": "down", "H": "home", "F": "end",
+	"1~": "home", "4~": "end", "5~": "pgup", "6~": "pgdown",
+}
+
+// parseKeys ... Split terminal input into key names. Printable keys are
+// named by themselves, others by names like "up", "enter" or "ctrl-c".
+func parseKeys(b []byte) []string {
+	var keys []string
+
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			if len(b) > 2 && (b[1] == '[' || b[1] == 'O') {
+				// Parameters until a final byte in 0x40-0x7e.
+				i := 2
+				for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+					i++
+				}
+
+				if i < len(b) {
+					if key, ok := csiKeys[string(b[2:i+1])]; ok {
+						keys = append(keys, key)
+					}
+
+					b = b[i+1:]
+					continue
+				}
+			}
+
+			keys = append(keys, "esc")
+			b = b[1:]
+			continue
+		}
+
+		r, size := utf8.DecodeRune(b)
+		b = b[size:]
+
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		case 0x03:
+			keys = append(keys, "ctrl-c")
+		case 0x02:
+			keys = append(keys, "pgup")
+		case 0x06:
+			keys = append(keys, "pgdown")
+		case 0x0e:
+			keys = append(keys, "down")
+		case 0x10:
+			keys = append(keys, "up")
+		default:
+			if r >= 0x20 && r != utf8.RuneError {
+				keys = append(keys, string(r))
+			}
+		}
+	}
+
+	return keys
+}
+
+// current ... Returns the selected server, nil if none matches the filter.
+func (t *tui) current() *ss.ShadowsocksURI {
+	if len(t.visible) == 0 {
+		return nil
+	}
+
+	return t.uris[t.visible[t.selected]]
+}
+
+// handle ... Act on a key.
+func (t *tui) handle(key string) {
+	if t.prompt != nil {
+		t.handlePrompt(key)
+		return
+	}
+
+	if t.choose != nil {
+		choose := t.choose
+		t.choose = nil
+		t.status = ""
+		choose(key)
+
+		return
+	}
+
+	quitting := t.quitting
+	t.quitting = false
+	t.status = ""
+
+	switch key {
+	case "q":
+		if t.modified && !quitting {
+			t.status = "unsaved changes, press q again to quit or s to save"
+			t.quitting = true
+
+			return
+		}
+
+		t.done = true
+	case "ctrl-c":
+		t.done = true
+	case "j", "down":
+		t.move(1)
+	case "k", "up":
+		t.move(-1)
+	case " ", "pgdown":
+		t.move(t.pageSize())
+	case "b", "pgup":
+		t.move(-t.pageSize())
+	case "g", "home":
+		t.move(-len(t.uris))
+	case "G", "end":
+		t.move(len(t.uris))
+	case "/":
+		t.ask("filter", t.filter, t.applyFilter)
+	case "esc":
+		t.applyFilter("")
+	case "y":
+		t.copy()
+	case "e":
+		if t.current() != nil {
+			t.status = "edit: [t]ag [h]ost [p]ort [m]ethod pass[w]ord plu[g]in plugin [o]ptions"
+			t.choose = t.edit
+		}
+	case "s":
+		t.save()
+	case "?":
+		t.help = !t.help
+	}
+}
+
+// handlePrompt ... Edit the prompt line.
+func (t *tui) handlePrompt(key string) {
+	p := t.prompt
+
+	switch key {
+	case "enter":
+		t.prompt = nil
+		p.done(string(p.text))
+	case "esc", "ctrl-c":
+		t.prompt = nil
+	case "backspace":
+		if len(p.text) > 0 {
+			p.text = p.text[:len(p.text)-1]
+		}
+	default:
+		if utf8.RuneCountInString(key) == 1 {
+			p.text = append(p.text, []rune(key)...)
+		}
+	}
+}
+
+// ask ... Prompt for a line prefilled with value, done receives the entered text.
+func (t *tui) ask(label, value string, done func(text string)) {
+	t.prompt = &tuiPrompt{label, []rune(value), done}
+}
+
+// move ... Move the selection by n servers.
+func (t *tui) move(n int) {
+	t.selected += n
+
+	if t.selected >= len(t.visible) {
+		t.selected = len(t.visible) - 1
+	}
+
+	if t.selected < 0 {
+		t.selected = 0
+	}
+}
+
+// pageSize ... Number of list rows on screen.
+func (t *tui) pageSize() int {
+	_, height := t.size()
+
+	if height > 3 {
+		return height - 2
+	}
+
+	return 1
+}
+
+// size ... Returns width and height of the terminal.
+func (t *tui) size() (int, int) {
+	width, height, err := term.GetSize(int(t.out.Fd()))
+	if err != nil {
+		return 80, 24
+	}
+
+	return width, height
+}
+
+// applyFilter ... Show servers matching spec, either a filter like in
+// "sub -filter" or text contained in tag or hostname. Keeps the selected
+// server selected if it still matches.
+func (t *tui) applyFilter(spec string) {
+	match := func(uri *ss.ShadowsocksURI) bool { return true }
+
+	if strings.Contains(spec, "=") {
+		f, err := ss.ParseFilter(spec)
+		if err != nil {
+			t.status = err.Error()
+			return
+		}
+
+		match = f
+	} else if spec != "" {
+		text := strings.ToLower(spec)
+
+		match = func(uri *ss.ShadowsocksURI) bool {
+			return strings.Contains(strings.ToLower(uri.Tag), text) ||
+				strings.Contains(strings.ToLower(uri.Remote.Hostname()), text)
+		}
+	}
+
+	previous := -1
+	if len(t.visible) > 0 {
+		previous = t.visible[t.selected]
+	}
+
+	t.filter = spec
+	t.visible = t.visible[:0]
+	t.selected = 0
+
+	for i, uri := range t.uris {
+		if !match(uri) {
+			continue
+		}
+
+		if i == previous {
+			t.selected = len(t.visible)
+		}
+
+		t.visible = append(t.visible, i)
+	}
+
+	if len(t.visible) == 0 {
+		t.status = "no server matches " + strconv.Quote(spec)
+	}
+}
+
+// copy ... Copy the SIP002 URI of the selected server, through -copy-cmd or
+// the OSC 52 escape most terminals, tmux and ssh sessions understand.
+func (t *tui) copy() {
+	uri := t.current()
+	if uri == nil {
+		return
+	}
+
+	text := redactServer(uri, t.redact, true).EncodeSIP002URI()
+
+	if len(t.copyCmd) > 0 {
+		cmd := exec.Command(t.copyCmd[0], t.copyCmd[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.status = fmt.Sprintf("copy failed: %v %s", err, bytes.TrimSpace(output))
+			return
+		}
+	} else {
+		fmt.Fprintf(t.out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	}
+
+	t.status = "copied URI of " + displayTag(uri)
+}
+
+// tuiField ... A field of a server editable in the tui.
+type tuiField struct {
+	name string // Field of ss.ParseEdit
+	get  func(uri *ss.ShadowsocksURI) string
+}
+
+// tuiFields ... Editable fields by key.
+var tuiFields = map[string]*tuiField{
+	"t": {"tag", func(uri *ss.ShadowsocksURI) string { return uri.Tag }},
+	"h": {"host", func(uri *ss.ShadowsocksURI) string { return uri.Remote.Hostname() }},
+	"p": {"port", func(uri *ss.ShadowsocksURI) string { return strconv.Itoa(uri.Remote.Port()) }},
+	"m": {"method", func(uri *ss.ShadowsocksURI) string { return uri.Auth.Method() }},
+	"w": {"password", func(uri *ss.ShadowsocksURI) string { return uri.Auth.Password() }},
+	"g": {"plugin", pluginString},
+	"o": {"plugin_opts", func(uri *ss.ShadowsocksURI) string {
+		if uri.Plugin == nil {
+			return ""
+		}
+
+		return uri.Plugin.OptionsString()
+	}},
+}
+
+// pluginString ... Returns plugin of server as "<name>[;<options>]", "" if none.
+func pluginString(uri *ss.ShadowsocksURI) string {
+	if uri.Plugin == nil {
+		return ""
+	}
+
+	if options := uri.Plugin.OptionsString(); options != "" {
+		return uri.Plugin.Name() + ";" + options
+	}
+
+	return uri.Plugin.Name()
+}
+
+// edit ... Prompt for a new value of the field chosen by key.
+func (t *tui) edit(key string) {
+	field, ok := tuiFields[key]
+	if !ok {
+		return
+	}
+
+	index := t.visible[t.selected]
+
+	// Secrets are not shown in the prompt either, the field starts empty.
+	value := field.get(t.uris[index])
+	if t.redact != ss.RedactNone && field.get(t.uris[index].StripSecrets()) != value {
+		value = ""
+	}
+
+	t.ask(field.name, value, func(value string) {
+		edit, err := ss.ParseEdit(field.name + "=" + value)
+		if err != nil {
+			t.status = err.Error()
+			return
+		}
+
+		edited, err := ss.ApplyEdits(t.uris[index], edit)
+		if err != nil {
+			t.status = fmt.Sprintf("invalid %s: %v", field.name, err)
+			return
+		}
+
+		t.uris[index] = edited
+		t.modified = true
+		t.status = field.name + " of " + displayTag(edited) + " changed"
+	})
+}
+
+// save ... Write all servers, not only those matching the filter, to -o.
+func (t *tui) save() {
+	if t.output == "-" {
+		t.status = "nowhere to save, run with -o file"
+		return
+	}
+
+	data, err := ss.EncodeFormat(t.uris, t.flavor)
+	if err == nil {
+		err = writeFileAtomic(t.output, data)
+	}
+
+	if err != nil {
+		t.status = "save failed: " + err.Error()
+		return
+	}
+
+	t.modified = false
+	t.status = fmt.Sprintf("wrote %d servers to %s", len(t.uris), t.output)
+}
+
+// draw ... Redraw the whole screen.
+func (t *tui) draw() {
+	width, height := t.size()
+
+	var b strings.Builder
+
+	b.WriteString("\x1b[H")
+
+	for i, line := range t.render(width, height) {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+
+		b.WriteString(line)
+		b.WriteString("\x1b[K")
+	}
+
+	b.WriteString("\x1b[J")
+
+	io.WriteString(t.out, b.String())
+}
+
+// render ... Returns the screen lines: the list of tags on the left, details
+// and QR code of the selected server on the right, status at the bottom.
+func (t *tui) render(width, height int) []string {
+	if height < 3 || width < 20 {
+		return []string{fitWidth("terminal too small", width)}
+	}
+
+	rows := height - 2
+
+	listWidth := 12
+	for _, i := range t.visible {
+		if w := displayWidth(displayTag(t.uris[i])) + 2; w > listWidth {
+			listWidth = w
+		}
+	}
+
+	if listWidth > width/3 {
+		listWidth = width / 3
+	}
+
+	detailWidth := width - listWidth - 1
+
+	// Scroll the list to keep the selection on screen.
+	if t.selected < t.top {
+		t.top = t.selected
+	}
+
+	if t.selected >= t.top+rows {
+		t.top = t.selected - rows + 1
+	}
+
+	if t.top > 0 && t.top > len(t.visible)-rows {
+		t.top = len(t.visible) - rows
+		if t.top < 0 {
+			t.top = 0
+		}
+	}
+
+	title := fmt.Sprintf("ssuri tui: %d/%d servers", len(t.visible), len(t.uris))
+	if t.filter != "" {
+		title += ", filter " + strconv.Quote(t.filter)
+	}
+
+	if t.modified {
+		title += ", modified"
+	}
+
+	lines := []string{"\x1b[1m" + fitWidth(title, width) + "\x1b[0m"}
+
+	details := t.details(detailWidth, rows)
+
+	for row := 0; row < rows; row++ {
+		item := fitWidth("", listWidth)
+
+		if n := t.top + row; n < len(t.visible) {
+			item = fitWidth(" "+displayTag(t.uris[t.visible[n]]), listWidth)
+
+			if n == t.selected {
+				item = "\x1b[7m" + item + "\x1b[0m"
+			}
+		}
+
+		line := item + "│"
+		if row < len(details) {
+			line += details[row]
+		}
+
+		lines = append(lines, line)
+	}
+
+	return append(lines, t.statusLine(width))
+}
+
+// details ... Returns the lines right of the list: help, or fields and QR
+// code of the selected server. Lines are at most width wide.
+func (t *tui) details(width, rows int) []string {
+	if t.help {
+		lines := []string{}
+		for _, line := range tuiHelp {
+			lines = append(lines, fitWidth(" "+line, width))
+		}
+
+		return lines
+	}
+
+	uri := t.current()
+	if uri == nil {
+		return nil
+	}
+
+	encoded := redactServer(uri, t.redact, true).EncodeSIP002URI()
+	uri = uri.Redact(t.redact)
+
+	plugin := pluginString(uri)
+	if plugin == "" {
+		plugin = "none"
+	}
+
+	lines := []string{}
+	for _, field := range [][2]string{
+		{"Tag", uri.Tag},
+		{"Server", uri.Remote.String()},
+		{"Method", uri.Auth.Method()},
+		{"Password", uri.Auth.Password()},
+		{"Plugin", plugin},
+		{"URI", encoded},
+	} {
+		lines = append(lines, fitWidth(fmt.Sprintf(" %-9s %s", field[0]+":", field[1]), width))
+	}
+
+	var qr bytes.Buffer
+	qrterminal.GenerateHalfBlock(encoded, qrterminal.L, &qr)
+
+	code := strings.Split(strings.TrimRight(qr.String(), "\n"), "\n")
+
+	if len(lines)+1+len(code) > rows || displayWidth(code[0])+1 > width {
+		return append(lines, "", fitWidth(" enlarge the terminal to see the QR code", width))
+	}
+
+	lines = append(lines, "")
+	for _, line := range code {
+		lines = append(lines, " "+line)
+	}
+
+	return lines
+}
+
+// statusLine ... Returns the prompt, the status message or key hints.
+func (t *tui) statusLine(width int) string {
+	if t.prompt != nil {
+		// Show the end of long input.
+		text := []rune(t.prompt.label + ": " + string(t.prompt.text))
+		for len(text) > 0 && displayWidth(string(text))+1 > width {
+			text = text[1:]
+		}
+
+		return fitWidth(string(text)+"_", width)
+	}
+
+	if t.status != "" {
+		return fitWidth(t.status, width)
+	}
+
+	return "\x1b[2m" + fitWidth("j/k move  / filter  y copy  e edit  s save  ? help  q quit", width) + "\x1b[0m"
+}
+
+// displayTag ... Returns tag of server, or its address if untagged.
+func displayTag(uri *ss.ShadowsocksURI) string {
+	if uri.Tag == "" {
+		return uri.Remote.String()
+	}
+
+	return uri.Tag
+}
+
+// fitWidth ... Pad or cut s to width columns, replacing control characters
+// which would break the layout.
+func fitWidth(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return '?'
+		}
+
+		return r
+	}, s)
+
+	if w := displayWidth(s); w <= width {
+		return s + strings.Repeat(" ", width-w)
+	}
+
+	var b strings.Builder
+
+	w := 0
+	for _, r := range s {
+		if w+runeWidth(r) > width-1 {
+			break
+		}
+
+		b.WriteRune(r)
+		w += runeWidth(r)
+	}
+
+	b.WriteString("…")
+
+	return b.String() + strings.Repeat(" ", width-w-1)
+}
+
+// displayWidth ... Returns the number of terminal columns s takes.
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+
+	return w
+}
+
+// runeWidth ... Returns the number of terminal columns r takes: none for
+// combining marks and joiners, two for wide characters like CJK and emoji.
+// Each regional indicator takes one column, so a flag of two takes two.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0x200d || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case unicode.Is(wideRunes, r):
+		return 2
+	}
+
+	return 1
+}
+
+// wideRunes ... Main East Asian Wide and Fullwidth ranges, see UAX #11,
+// and emoji presented as wide by terminals.
+var wideRunes = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1},
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x2329, Hi: 0x232a, Stride: 1},
+		{Lo: 0x23e9, Hi: 0x23ec, Stride: 1},
+		{Lo: 0x23f0, Hi: 0x23f3, Stride: 3},
+		{Lo: 0x25fd, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2614, Hi: 0x2615, Stride: 1},
+		{Lo: 0x2648, Hi: 0x2653, Stride: 1},
+		{Lo: 0x267f, Hi: 0x2693, Stride: 20},
+		{Lo: 0x26a1, Hi: 0x26a1, Stride: 1},
+		{Lo: 0x26aa, Hi: 0x26ab, Stride: 1},
+		{Lo: 0x26bd, Hi: 0x26be, Stride: 1},
+		{Lo: 0x26c4, Hi: 0x26c5, Stride: 1},
+		{Lo: 0x26ce, Hi: 0x26d4, Stride: 6},
+		{Lo: 0x26ea, Hi: 0x26ea, Stride: 1},
+		{Lo: 0x26f2, Hi: 0x26f3, Stride: 1},
+		{Lo: 0x26f5, Hi: 0x26fa, Stride: 5},
+		{Lo: 0x26fd, Hi: 0x2705, Stride: 8},
+		{Lo: 0x270a, Hi: 0x270b, Stride: 1},
+		{Lo: 0x2728, Hi: 0x274c, Stride: 36},
+		{Lo: 0x274e, Hi: 0x274e, Stride: 1},
+		{Lo: 0x2753, Hi: 0x2755, Stride: 1},
+		{Lo: 0x2757, Hi: 0x2757, Stride: 1},
+		{Lo: 0x2795, Hi: 0x2797, Stride: 1},
+		{Lo: 0x27b0, Hi: 0x27bf, Stride: 15},
+		{Lo: 0x2b1b, Hi: 0x2b1c, Stride: 1},
+		{Lo: 0x2b50, Hi: 0x2b55, Stride: 5},
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1},
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1},
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1},
+		{Lo: 0x4e00, Hi: 0xa4cf, Stride: 1},
+		{Lo: 0xa960, Hi: 0xa97f, Stride: 1},
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfe10, Hi: 0xfe19, Stride: 1},
+		{Lo: 0xfe30, Hi: 0xfe6f, Stride: 1},
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1},
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x16fe0, Hi: 0x16fe4, Stride: 1},
+		{Lo: 0x17000, Hi: 0x18aff, Stride: 1},
+		{Lo: 0x1b000, Hi: 0x1b2ff, Stride: 1},
+		{Lo: 0x1f004, Hi: 0x1f004, Stride: 1},
+		{Lo: 0x1f0cf, Hi: 0x1f0cf, Stride: 1},
+		{Lo: 0x1f18e, Hi: 0x1f18e, Stride: 1},
+		{Lo: 0x1f191, Hi: 0x1f19a, Stride: 1},
+		{Lo: 0x1f200, Hi: 0x1f202, Stride: 1},
+		{Lo: 0x1f210, Hi: 0x1f23b, Stride: 1},
+		{Lo: 0x1f240, Hi: 0x1f248, Stride: 1},
+		{Lo: 0x1f250, Hi: 0x1f251, Stride: 1},
+		{Lo: 0x1f260, Hi: 0x1f265, Stride: 1},
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f680, Hi: 0x1f6ff, Stride: 1},
+		{Lo: 0x1f7e0, Hi: 0x1f7eb, Stride: 1},
+		{Lo: 0x1f90c, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x1fa70, Hi: 0x1faff, Stride: 1},
+		{Lo: 0x20000, Hi: 0x2fffd, Stride: 1},
+		{Lo: 0x30000, Hi: 0x3fffd, Stride: 1},
+	},
+}
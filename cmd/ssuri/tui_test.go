@@ -0,0 +1,254 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+// newTestTUI ... Returns tui of a few servers, all visible.
+func newTestTUI() *tui {
+	uris := []*ss.ShadowsocksURI{}
+
+	for _, s := range []struct {
+		tag  string
+		host string
+		port int
+	}{
+		{"HK-01", "hk.example.com", 8388},
+		{"🇯🇵 東京", "jp.example.com", 443},
+		{"", "192.168.100.1", 8888},
+		{"HK-02", "hk2.example.com", 8443},
+	} {
+		uris = append(uris, &ss.ShadowsocksURI{
+			Remote: ss.NewServer(s.host, s.port),
+			Auth:   ss.NewAuthInfo("aes-256-gcm", "correct horse"),
+			Tag:    s.tag,
+		})
+	}
+
+	t := &tui{uris: uris}
+	t.applyFilter("")
+
+	return t
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"jk", []string{"j", "k"}},
+		{"\x1b[A\x1b[B", []string{"up", "down"}},
+		{"\x1bOH\x1b[4~", []string{"home", "end"}},
+		{"\x1b[5~\x1b[6~", []string{"pgup", "pgdown"}},
+		{"\x1b", []string{"esc"}},
+		{"\x1b[1;5C", nil},
+		{"\r\n\x7f\x03", []string{"enter", "enter", "backspace", "ctrl-c"}},
+		{"\x02\x06\x0e\x10", []string{"pgup", "pgdown", "down", "up"}},
+		{"é東🔑", []string{"é", "東", "🔑"}},
+		{"\x01\xff", nil},
+	}
+
+	for i, ut := range tests {
+		if keys := parseKeys([]byte(ut.input)); !reflect.DeepEqual(keys, ut.expected) {
+			t.Errorf("#%d test failed. Expected: %q, Got: %q", i, ut.expected, keys)
+		}
+	}
+}
+
+func TestFitWidth(t *testing.T) {
+	tests := []struct {
+		s        string
+		width    int
+		expected string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 5, "abcd…"},
+		{"abc", 0, ""},
+		{"a\tb\x1b", 4, "a?b?"},
+		{"東京", 5, "東京 "},
+		{"東京大阪", 5, "東京…"},
+		{"東京大阪", 6, "東京… "},
+		{"🇯🇵 Tokyo", 6, "🇯🇵 To…"},
+		{"🔑key", 4, "🔑k…"},
+		{"éte", 4, "éte "},
+	}
+
+	for i, ut := range tests {
+		s := fitWidth(ut.s, ut.width)
+		if s != ut.expected || displayWidth(s) != ut.width {
+			t.Errorf("#%d test failed. Expected: %q, Got: %q (%d columns)", i, ut.expected, s, displayWidth(s))
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected int
+	}{
+		{"", 0},
+		{"HK-01", 5},
+		{"東京", 4},
+		{"서울", 4},
+		{"ＡＢ", 4},
+		{"🇯🇵", 2},
+		{"🔑", 2},
+		{"✅", 2},
+		{"é", 1},
+		{"👩‍💻", 4},
+		{"▀▄█", 3},
+	}
+
+	for i, ut := range tests {
+		if w := displayWidth(ut.s); w != ut.expected {
+			t.Errorf("#%d test failed. Expected: %d, Got: %d for %q", i, ut.expected, w, ut.s)
+		}
+	}
+}
+
+func TestApplyFilter(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []int
+		status   bool
+	}{
+		{"", []int{0, 1, 2, 3}, false},
+		{"hk", []int{0, 3}, false},
+		{"東京", []int{1}, false},
+		{"192.168", []int{2}, false},
+		{"port=8388", []int{0}, false},
+		{"tag!=^HK", []int{1, 2}, false},
+		{"nothing", []int{}, true},
+	}
+
+	for i, ut := range tests {
+		tui := newTestTUI()
+		tui.applyFilter(ut.spec)
+
+		if !reflect.DeepEqual(tui.visible, ut.expected) || tui.filter != ut.spec || (tui.status != "") != ut.status {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v %q", i, ut.expected, tui.visible, tui.status)
+		}
+	}
+
+	// The selected server stays selected if it still matches.
+	tui := newTestTUI()
+	tui.move(3)
+	tui.applyFilter("hk")
+
+	if tui.current() != tui.uris[3] {
+		t.Errorf("Expected selection kept, Got: %v", tui.current())
+	}
+
+	// Invalid filters keep the list as it is.
+	tui.applyFilter("port=x")
+
+	if tui.filter != "hk" || tui.status == "" {
+		t.Errorf("Expected invalid filter rejected, Got: %q %q", tui.filter, tui.status)
+	}
+}
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		moves    []int
+		expected int
+	}{
+		{nil, 0},
+		{[]int{1}, 1},
+		{[]int{1, 1, -1}, 1},
+		{[]int{-1}, 0},
+		{[]int{10}, 3},
+		{[]int{10, -2}, 1},
+	}
+
+	for i, ut := range tests {
+		tui := newTestTUI()
+		for _, n := range ut.moves {
+			tui.move(n)
+		}
+
+		if tui.selected != ut.expected {
+			t.Errorf("#%d test failed. Expected: %d, Got: %d", i, ut.expected, tui.selected)
+		}
+	}
+
+	// Nothing to select without visible servers.
+	tui := newTestTUI()
+	tui.applyFilter("nothing")
+	tui.move(1)
+
+	if tui.selected != 0 || tui.current() != nil {
+		t.Errorf("Expected no selection, Got: %d", tui.selected)
+	}
+}
+
+// sgr ... Select graphic rendition escapes of rendered lines.
+var sgr = regexp.MustCompile("\x1b\\[[0-9]*m")
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		width  int
+		height int
+		help   bool
+	}{
+		{80, 24, false},
+		{120, 40, false},
+		{60, 10, false},
+		{80, 24, true},
+		{30, 4, false},
+	}
+
+	for i, ut := range tests {
+		tui := newTestTUI()
+		tui.help = ut.help
+		tui.move(1)
+
+		lines := tui.render(ut.width, ut.height)
+		if len(lines) != ut.height {
+			t.Errorf("#%d test failed. Expected %d lines, Got: %d", i, ut.height, len(lines))
+		}
+
+		for j, line := range lines {
+			if w := displayWidth(sgr.ReplaceAllString(line, "")); w > ut.width {
+				t.Errorf("#%d test failed. Line %d is %d columns wide: %q", i, j, w, line)
+			}
+		}
+
+		if !strings.Contains(lines[0], "4/4 servers") {
+			t.Errorf("#%d test failed. Unexpected title: %q", i, lines[0])
+		}
+
+		if !strings.Contains(lines[2], "\x1b[7m 🇯🇵 東京") {
+			t.Errorf("#%d test failed. Expected selected server highlighted, Got: %q", i, lines[2])
+		}
+	}
+
+	// Details of the selected server are shown, untagged servers by address.
+	tui := newTestTUI()
+	tui.move(2)
+	screen := strings.Join(tui.render(100, 40), "\n")
+
+	for _, s := range []string{"192.168.100.1:8888", "correct horse", "ss://"} {
+		if !strings.Contains(screen, s) {
+			t.Errorf("Expected %q on screen", s)
+		}
+	}
+
+	if lines := newTestTUI().render(19, 24); len(lines) != 1 || !strings.Contains(lines[0], "too small") {
+		t.Errorf("Expected terminal too small, Got: %q", lines)
+	}
+}
+
+func TestRenderRedacted(t *testing.T) {
+	tui := newTestTUI()
+	tui.redact = ss.RedactFull
+	screen := strings.Join(tui.render(100, 40), "\n")
+
+	if strings.Contains(screen, "correct horse") || !strings.Contains(screen, ss.RedactMask) {
+		t.Errorf("Expected password masked on screen")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+// watchFlags ... Flags re-running a command whenever its input file changes.
+type watchFlags struct {
+	watch    *bool
+	interval *time.Duration
+	qr       *bool
+}
+
+// addWatchFlags ... Register -watch, -interval and -qr.
+func addWatchFlags(fs *flag.FlagSet) *watchFlags {
+	return &watchFlags{
+		watch:    fs.Bool("watch", false, "keep running and convert again whenever -i changes, rewriting the outputs atomically"),
+		interval: fs.Duration("interval", time.Second, "how often -watch polls -i"),
+		qr:       fs.Bool("qr", false, "with -watch, print QR codes of the servers to stderr after each change"),
+	}
+}
+
+// check ... Reject -interval and -qr without -watch, printing an error.
+func (w *watchFlags) check(fs *flag.FlagSet) bool {
+	ok := true
+
+	fs.Visit(func(f *flag.Flag) {
+		if !*w.watch && (f.Name == "interval" || f.Name == "qr") {
+			fmt.Fprintf(fs.Output(), "-%s needs -watch\n", f.Name)
+			ok = false
+		}
+	})
+
+	return ok
+}
+
+// updateFunc ... Converts input and writes the outputs, returns the servers.
+// Data is trimmed and starts at firstLine of the input, see trimInput.
+type updateFunc func(data string, firstLine int) ([]*ss.ShadowsocksURI, error)
+
+// run ... Poll the input file and update the outputs whenever its content
+// changes, until interrupted. QR codes strip the secrets redacted by redact.
+func (w *watchFlags) run(files *ioFlags, redact ss.RedactMode, update updateFunc) int {
+	if *files.input == "-" {
+		fmt.Fprintf(os.Stderr, "-watch needs an input file\n")
+		return exitUsage
+	}
+
+	if *w.interval <= 0 {
+		fmt.Fprintf(os.Stderr, "invalid -interval %v\n", *w.interval)
+		return exitUsage
+	}
+
+	wt := &watcher{input: *files.input, update: update, qr: *w.qr, redact: redact, log: os.Stderr}
+
+	for ; ; time.Sleep(*w.interval) {
+		wt.poll()
+	}
+}
+
+// watcher ... State of -watch between polls.
+type watcher struct {
+	input  string
+	update updateFunc
+	qr     bool          // Print QR codes after each update
+	redact ss.RedactMode // Secrets stripped from QR codes
+	log    io.Writer     // Progress and errors
+
+	last    []byte // Input of the last update
+	lastErr string // Last error reading the input
+}
+
+// poll ... Read the input and update the outputs if it changed since the
+// last poll, reports whether they were updated. Failed updates are logged and
+// leave the outputs alone, unreadable input is logged once.
+func (w *watcher) poll() bool {
+	data, err := ioutil.ReadFile(w.input)
+	if err != nil {
+		// Report once, the file may be replaced in a moment.
+		if err.Error() != w.lastErr {
+			fmt.Fprintf(w.log, "%v\n", err)
+			w.lastErr = err.Error()
+		}
+
+		return false
+	}
+
+	w.lastErr = ""
+
+	if w.last != nil && bytes.Equal(data, w.last) {
+		return false
+	}
+
+	w.last = data
+
+	uris, err := w.update(trimInput(string(data)))
+	if err != nil {
+		fmt.Fprintf(w.log, "%s: %v\n", w.input, err)
+		return false
+	}
+
+	fmt.Fprintf(w.log, "%s: updated %d servers\n", time.Now().Format("15:04:05"), len(uris))
+
+	if w.qr {
+		for _, uri := range uris {
+			fmt.Fprintf(w.log, "%s\n", uri.Tag)
+			// Servers may be masked for the outputs, which URIs must not carry.
+			generateShadowsocksQRCode(redactServer(uri, w.redact, true), false, w.log)
+		}
+	}
+
+	return true
+}
+
+// writeFileAtomic ... Replace file name with data through a temporary file in
+// the same directory, so readers never see a partial file. Keeps the mode of
+// an existing file. Anything but a regular file, e.g. /dev/stdout, is written
+// in place.
+func writeFileAtomic(name string, data []byte) error {
+	mode := os.FileMode(0644)
+
+	if info, err := os.Stat(name); err == nil {
+		if !info.Mode().IsRegular() {
+			return ioutil.WriteFile(name, data, info.Mode())
+		}
+
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+
+	if serr := tmp.Sync(); err == nil {
+		err = serr
+	}
+
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	return err
+}
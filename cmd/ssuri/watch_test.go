@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "out.txt")
+
+	tests := []struct {
+		data []byte
+		mode os.FileMode // Set before writing if not zero
+	}{
+		{[]byte("first\n"), 0},
+		{[]byte("second, longer than the first\n"), 0},
+		{[]byte(""), 0},
+		{[]byte("private\n"), 0600},
+	}
+
+	for i, ut := range tests {
+		if ut.mode != 0 {
+			os.Chmod(name, ut.mode)
+		}
+
+		if err := writeFileAtomic(name, ut.data); err != nil {
+			t.Fatalf("#%d test failed. %v", i, err)
+		}
+
+		data, err := ioutil.ReadFile(name)
+		if err != nil || !bytes.Equal(data, ut.data) {
+			t.Errorf("#%d test failed. Expected: %q, Got: %q, %v", i, ut.data, data, err)
+		}
+
+		expected := ut.mode
+		if expected == 0 {
+			expected = 0644
+		}
+
+		if info, err := os.Stat(name); err != nil || info.Mode().Perm() != expected {
+			t.Errorf("#%d test failed. Expected mode %v, Got: %v", i, expected, info.Mode())
+		}
+	}
+
+	// Failures leave no temporary files behind.
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "out.txt"), []byte("x")); err == nil {
+		t.Errorf("Expected error for missing directory")
+	}
+
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the output in %s, Got: %d files", dir, len(entries))
+	}
+}
+
+func TestWatcherPoll(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.txt")
+	output := filepath.Join(dir, "out.txt")
+
+	// Writes the upper-cased input, fails on "bad".
+	update := func(data string, _ int) ([]*ss.ShadowsocksURI, error) {
+		if data == "bad" {
+			return nil, errors.New("bad input")
+		}
+
+		return nil, writeFileAtomic(output, []byte(strings.ToUpper(data)))
+	}
+
+	var log bytes.Buffer
+	w := &watcher{input: input, update: update, log: &log}
+
+	tests := []struct {
+		input    string // Written to the input file unless empty, "-" removes it
+		updated  bool
+		output   string
+		messages int // Lines logged since the last step
+	}{
+		{"-", false, "", 1},
+		{"-", false, "", 0},
+		{"one", true, "ONE", 1},
+		{"", false, "ONE", 0},
+		{"two\n", true, "TWO", 1},
+		{"two\n", false, "TWO", 0},
+		{"bad", false, "TWO", 1},
+		{"", false, "TWO", 0},
+		{"-", false, "TWO", 1},
+		{"three", true, "THREE", 1},
+	}
+
+	for i, ut := range tests {
+		switch ut.input {
+		case "":
+		case "-":
+			os.Remove(input)
+		default:
+			ioutil.WriteFile(input, []byte(ut.input), 0644)
+		}
+
+		log.Reset()
+
+		if updated := w.poll(); updated != ut.updated {
+			t.Errorf("#%d test failed. Expected updated: %v, Got: %v", i, ut.updated, updated)
+		}
+
+		data, _ := ioutil.ReadFile(output)
+		if string(data) != ut.output {
+			t.Errorf("#%d test failed. Expected: %q, Got: %q", i, ut.output, data)
+		}
+
+		if n := strings.Count(log.String(), "\n"); n != ut.messages {
+			t.Errorf("#%d test failed. Expected %d messages, Got: %q", i, ut.messages, log.String())
+		}
+	}
+}
+
+func TestWatchFlagsCheck(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected bool
+	}{
+		{nil, true},
+		{[]string{"-watch"}, true},
+		{[]string{"-watch", "-interval", "5s", "-qr"}, true},
+		{[]string{"-interval", "5s"}, false},
+		{[]string{"-qr"}, false},
+		{[]string{"-watch=false", "-qr"}, false},
+	}
+
+	for i, ut := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		watch := addWatchFlags(fs)
+
+		if err := fs.Parse(ut.args); err != nil {
+			t.Fatalf("#%d test failed. %v", i, err)
+		}
+
+		if ok := watch.check(fs); ok != ut.expected {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v", i, ut.expected, ok)
+		}
+	}
+}
+
+func TestWatcherQRRedacted(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.txt")
+	ioutil.WriteFile(input, []byte("x"), 0644)
+
+	uri := &ss.ShadowsocksURI{
+		Remote: ss.NewServer("192.168.100.1", 8888),
+		Auth:   ss.NewAuthInfo("aes-256-gcm", "correct horse"),
+		Tag:    "a",
+	}
+
+	tests := []struct {
+		redact   ss.RedactMode
+		expected *ss.ShadowsocksURI // Server of the QR code
+	}{
+		{ss.RedactNone, uri},
+		{ss.RedactPartial, uri.StripSecrets()},
+		{ss.RedactFull, uri.StripSecrets()},
+	}
+
+	for i, ut := range tests {
+		// Outputs other than URIs get masked servers.
+		update := func(data string, _ int) ([]*ss.ShadowsocksURI, error) {
+			return []*ss.ShadowsocksURI{uri.Redact(ut.redact)}, nil
+		}
+
+		var log, expected bytes.Buffer
+		w := &watcher{input: input, update: update, qr: true, redact: ut.redact, log: &log}
+		w.poll()
+
+		generateShadowsocksQRCode(ut.expected, false, &expected)
+
+		if !strings.Contains(log.String(), expected.String()) {
+			t.Errorf("#%d test failed. Expected QR code of %s", i, ut.expected.EncodeSIP002URI())
+		}
+	}
+}
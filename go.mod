@@ -1,5 +1,12 @@
 module github.com/vgxbj/ssuri
 
-go 1.12
+go 1.17
 
-require github.com/mdp/qrterminal v1.0.1
+require (
+	github.com/mdp/qrterminal v1.0.1
+	golang.org/x/crypto v0.9.0
+	golang.org/x/term v0.10.0
+	rsc.io/qr v0.2.0
+)
+
+require golang.org/x/sys v0.10.0 // indirect
@@ -0,0 +1,333 @@
+package ss
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// aeadMethod ... Key size and constructor of an AEAD method.
+type aeadMethod struct {
+	keySize int
+	new     func(key []byte) (cipher.AEAD, error)
+}
+
+// aeadMethods ... AEAD methods implemented by AEADCipher, see SIP004.
+var aeadMethods = map[string]*aeadMethod{
+	"aes-128-gcm":             {16, newGCM},
+	"aes-192-gcm":             {24, newGCM},
+	"aes-256-gcm":             {32, newGCM},
+	"chacha20-ietf-poly1305":  {32, chacha20poly1305.New},
+	"xchacha20-ietf-poly1305": {32, chacha20poly1305.NewX},
+}
+
+// aeadMaxPayload ... Largest payload of a chunk of a TCP stream.
+const aeadMaxPayload = 0x3FFF
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// AEADCipher ... Shadowsocks AEAD cipher of a method and password, see SIP004.
+type AEADCipher struct {
+	method *aeadMethod
+	key    []byte
+}
+
+// NewAEADCipher ... Returns AEAD cipher, stream ciphers are not supported.
+func NewAEADCipher(method, password string) (*AEADCipher, error) {
+	m, ok := aeadMethods[strings.ToLower(method)]
+	if !ok {
+		return nil, errors.New("unsupported method: " + method)
+	}
+
+	return &AEADCipher{method: m, key: passwordKey(password, m.keySize)}, nil
+}
+
+// passwordKey ... Derive master key from password like OpenSSL EVP_BytesToKey with MD5.
+func passwordKey(password string, size int) []byte {
+	var key, prev []byte
+
+	for len(key) < size {
+		h := md5.New()
+		h.Write(prev)
+		h.Write([]byte(password))
+		prev = h.Sum(nil)
+		key = append(key, prev...)
+	}
+
+	return key[:size]
+}
+
+// saltSize ... Size of salt, equal to the key size.
+func (c *AEADCipher) saltSize() int {
+	return c.method.keySize
+}
+
+// newAEAD ... Returns AEAD of the session subkey derived from salt.
+func (c *AEADCipher) newAEAD(salt []byte) (cipher.AEAD, error) {
+	subkey := make([]byte, c.method.keySize)
+
+	if _, err := io.ReadFull(hkdf.New(sha1.New, c.key, salt, []byte("ss-subkey")), subkey); err != nil {
+		return nil, err
+	}
+
+	return c.method.new(subkey)
+}
+
+// StreamConn ... Returns conn encrypting writes and decrypting reads as a
+// shadowsocks TCP stream. Each direction starts with its own salt.
+func (c *AEADCipher) StreamConn(conn net.Conn) net.Conn {
+	return &aeadConn{Conn: conn, cipher: c}
+}
+
+// aeadConn ... Encrypted TCP stream of chunks [length][length tag][payload][payload tag].
+type aeadConn struct {
+	net.Conn
+	cipher *AEADCipher
+
+	writer cipher.AEAD
+	wNonce []byte
+
+	reader   cipher.AEAD
+	rNonce   []byte
+	leftover []byte // decrypted but unread payload
+}
+
+// Write ... Implements io.Writer.
+func (c *aeadConn) Write(p []byte) (int, error) {
+	var buf []byte
+
+	if c.writer == nil {
+		salt := make([]byte, c.cipher.saltSize())
+		if _, err := rand.Read(salt); err != nil {
+			return 0, err
+		}
+
+		aead, err := c.cipher.newAEAD(salt)
+		if err != nil {
+			return 0, err
+		}
+
+		c.writer = aead
+		c.wNonce = make([]byte, aead.NonceSize())
+		buf = salt
+	}
+
+	n := 0
+
+	for n < len(p) {
+		payload := p[n:]
+		if len(payload) > aeadMaxPayload {
+			payload = payload[:aeadMaxPayload]
+		}
+
+		size := []byte{byte(len(payload) >> 8), byte(len(payload))}
+		buf = c.writer.Seal(buf, c.wNonce, size, nil)
+		incrementNonce(c.wNonce)
+		buf = c.writer.Seal(buf, c.wNonce, payload, nil)
+		incrementNonce(c.wNonce)
+
+		n += len(payload)
+	}
+
+	if _, err := c.Conn.Write(buf); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Read ... Implements io.Reader.
+func (c *aeadConn) Read(p []byte) (int, error) {
+	// Chunks without payload are skipped rather than returned as (0, nil).
+	for len(c.leftover) == 0 {
+		payload, err := c.readChunk()
+		if err != nil {
+			return 0, err
+		}
+
+		c.leftover = payload
+	}
+
+	n := copy(p, c.leftover)
+	c.leftover = c.leftover[n:]
+
+	return n, nil
+}
+
+// readChunk ... Read and decrypt the next chunk.
+func (c *aeadConn) readChunk() ([]byte, error) {
+	if c.reader == nil {
+		salt := make([]byte, c.cipher.saltSize())
+		if _, err := io.ReadFull(c.Conn, salt); err != nil {
+			return nil, err
+		}
+
+		aead, err := c.cipher.newAEAD(salt)
+		if err != nil {
+			return nil, err
+		}
+
+		c.reader = aead
+		c.rNonce = make([]byte, aead.NonceSize())
+	}
+
+	overhead := c.reader.Overhead()
+
+	buf := make([]byte, 2+overhead)
+	if _, err := io.ReadFull(c.Conn, buf); err != nil {
+		return nil, err
+	}
+
+	size, err := c.reader.Open(buf[:0], c.rNonce, buf, nil)
+	if err != nil {
+		return nil, errAEADAuth
+	}
+	incrementNonce(c.rNonce)
+
+	length := (int(size[0])<<8 | int(size[1])) & aeadMaxPayload
+
+	buf = make([]byte, length+overhead)
+	if _, err := io.ReadFull(c.Conn, buf); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	payload, err := c.reader.Open(buf[:0], c.rNonce, buf, nil)
+	if err != nil {
+		return nil, errAEADAuth
+	}
+	incrementNonce(c.rNonce)
+
+	return payload, nil
+}
+
+// CloseWrite ... Shut down the writing side if the underlying connection supports it.
+func (c *aeadConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+
+	return nil
+}
+
+// errAEADAuth ... Wrong password or method, or a tampered stream.
+var errAEADAuth = errors.New("cipher: message authentication failed, wrong password or method")
+
+// unexpectedEOF ... Turn EOF in the middle of a chunk into io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// incrementNonce ... Increment little endian nonce by one.
+func incrementNonce(nonce []byte) {
+	for i := range nonce {
+		nonce[i]++
+		if nonce[i] != 0 {
+			return
+		}
+	}
+}
+
+// Address types of SOCKS5 and shadowsocks target addresses.
+const (
+	socksAddrIPv4   = 1
+	socksAddrDomain = 3
+	socksAddrIPv6   = 4
+)
+
+// AppendSocksAddr ... Append "host:port" to b in SOCKS5 address format, as
+// sent in front of the payload of shadowsocks connections.
+func AppendSocksAddr(b []byte, addr string) ([]byte, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return nil, errors.New("invalid port: " + portStr)
+	}
+
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) == 0 || len(host) > 255 {
+			return nil, errors.New("invalid hostname: " + host)
+		}
+
+		b = append(b, socksAddrDomain, byte(len(host)))
+		b = append(b, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		b = append(b, socksAddrIPv4)
+		b = append(b, ip4...)
+	} else {
+		b = append(b, socksAddrIPv6)
+		b = append(b, ip.To16()...)
+	}
+
+	return append(b, byte(port>>8), byte(port)), nil
+}
+
+// ReadSocksAddr ... Read an address in SOCKS5 format, returns "host:port".
+func ReadSocksAddr(r io.Reader) (string, error) {
+	var atyp [1]byte
+	if _, err := io.ReadFull(r, atyp[:]); err != nil {
+		return "", err
+	}
+
+	var host string
+
+	switch atyp[0] {
+	case socksAddrIPv4, socksAddrIPv6:
+		ip := make([]byte, net.IPv4len)
+		if atyp[0] == socksAddrIPv6 {
+			ip = make([]byte, net.IPv6len)
+		}
+
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", unexpectedEOF(err)
+		}
+
+		host = net.IP(ip).String()
+	case socksAddrDomain:
+		var length [1]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return "", unexpectedEOF(err)
+		}
+
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(r, domain); err != nil {
+			return "", unexpectedEOF(err)
+		}
+
+		host = string(domain)
+	default:
+		return "", errors.New("invalid address type")
+	}
+
+	var port [2]byte
+	if _, err := io.ReadFull(r, port[:]); err != nil {
+		return "", unexpectedEOF(err)
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))), nil
+}
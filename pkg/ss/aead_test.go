@@ -0,0 +1,116 @@
+package ss_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestAEADStream(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 3000) // several chunks
+
+	for _, method := range []string{"aes-128-gcm", "aes-192-gcm", "aes-256-gcm", "chacha20-ietf-poly1305", "xchacha20-ietf-poly1305"} {
+		client, err := ss.NewAEADCipher(method, "correct horse")
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		server, _ := ss.NewAEADCipher(method, "correct horse")
+
+		c1, c2 := net.Pipe()
+		w, r := client.StreamConn(c1), server.StreamConn(c2)
+
+		go func() {
+			w.Write(payload[:10])
+			w.Write(payload[10:])
+			w.Close()
+		}()
+
+		got, err := ioutil.ReadAll(r)
+		if err != nil || !bytes.Equal(got, payload) {
+			t.Errorf("%s: round trip failed, %d of %d bytes: %v", method, len(got), len(payload), err)
+		}
+	}
+
+	// Chunks without payload are skipped by Read.
+	client, _ := ss.NewAEADCipher("aes-256-gcm", "correct horse")
+
+	c1, c2 := net.Pipe()
+	w, r := client.StreamConn(c1), client.StreamConn(c2)
+
+	go func() {
+		ss.WriteEmptyChunk(w)
+		w.Write(payload[:10])
+		ss.WriteEmptyChunk(w)
+		ss.WriteEmptyChunk(w)
+		w.Write(payload[10:20])
+		w.Close()
+	}()
+
+	buf := make([]byte, 100)
+	for _, expected := range [][]byte{payload[:10], payload[10:20]} {
+		if n, err := r.Read(buf); err != nil || !bytes.Equal(buf[:n], expected) {
+			t.Errorf("Expected %q after empty chunks, Got: %q, %v", expected, buf[:n], err)
+		}
+	}
+
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("Expected EOF, Got: %d, %v", n, err)
+	}
+
+	server, _ := ss.NewAEADCipher("aes-256-gcm", "wrong horse")
+
+	c1, c2 = net.Pipe()
+	go func() {
+		client.StreamConn(c1).Write(payload[:100])
+		c1.Close()
+	}()
+
+	if _, err := ioutil.ReadAll(server.StreamConn(c2)); err == nil {
+		t.Errorf("Expected authentication error for wrong password")
+	}
+
+	for _, method := range []string{"rc4-md5", "aes-256-cfb", "none"} {
+		if _, err := ss.NewAEADCipher(method, "test"); err == nil {
+			t.Errorf("Expected error for stream method %v", method)
+		}
+	}
+}
+
+func TestSocksAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		size int
+	}{
+		{"192.168.100.1:8888", 1 + 4 + 2},
+		{"[2001:db8::1]:443", 1 + 16 + 2},
+		{"example.com:80", 1 + 1 + 11 + 2},
+	}
+
+	for i, ut := range tests {
+		b, err := ss.AppendSocksAddr(nil, ut.addr)
+		if err != nil || len(b) != ut.size {
+			t.Errorf("#%d test failed. Expected %d bytes, Got: %v, %v", i, ut.size, b, err)
+			continue
+		}
+
+		addr, err := ss.ReadSocksAddr(bytes.NewReader(b))
+		if err != nil || addr != ut.addr {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v, %v", i, ut.addr, addr, err)
+		}
+
+		if _, err := ss.ReadSocksAddr(bytes.NewReader(b[:len(b)-1])); err != io.ErrUnexpectedEOF {
+			t.Errorf("#%d test failed. Expected unexpected EOF, Got: %v", i, err)
+		}
+	}
+
+	for _, addr := range []string{"example.com", "example.com:70000", ":80"} {
+		if _, err := ss.AppendSocksAddr(nil, addr); err == nil {
+			t.Errorf("Expected error for %v", addr)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package ss
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"strconv"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Bundle file format, version 1. All parts but the ciphertext are
+// authenticated as additional data:
+//
+//	magic      4 bytes  "SSUB"
+//	version    1 byte   1
+//	log2(N)    1 byte   scrypt cost
+//	r          1 byte   scrypt block size
+//	p          1 byte   scrypt parallelization
+//	salt      16 bytes
+//	nonce     24 bytes  XChaCha20-Poly1305 nonce
+//	ciphertext          SIP002 URIs, one per line, sealed with the scrypt key
+const (
+	bundleMagic   = "SSUB"
+	bundleVersion = 1
+
+	bundleSaltSize   = 16
+	bundleHeaderSize = len(bundleMagic) + 4 + bundleSaltSize + chacha20poly1305.NonceSizeX
+
+	// Default scrypt cost, about 100ms and 32MB on current hardware.
+	bundleLogN = 15
+	bundleR    = 8
+	bundleP    = 1
+
+	// Limits of scrypt parameters accepted by OpenBundle, so a crafted
+	// bundle cannot exhaust memory or CPU. Scrypt needs 128·N·r bytes and
+	// p times the work of one pass.
+	bundleMaxLogN   = 20
+	bundleMaxR      = 32
+	bundleMaxP      = 4
+	bundleMaxMemory = 256 << 20
+)
+
+// ErrBundleDecrypt ... Wrong passphrase, or a bundle modified after sealing.
+var ErrBundleDecrypt = errors.New("invalid passphrase or corrupted bundle")
+
+// SealBundle ... Encrypt servers into a bundle with a key derived from passphrase.
+func SealBundle(uris []*ShadowsocksURI, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+
+	plaintext, err := EncodeFormat(uris, "sip002")
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, bundleHeaderSize)
+	copy(header, bundleMagic)
+	header[4] = bundleVersion
+	header[5] = bundleLogN
+	header[6] = bundleR
+	header[7] = bundleP
+
+	if _, err := rand.Read(header[8:]); err != nil {
+		return nil, err
+	}
+
+	aead, err := newBundleAEAD(header, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := header[8+bundleSaltSize:]
+
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// OpenBundle ... Decrypt servers from a bundle sealed by SealBundle.
+func OpenBundle(data []byte, passphrase string) ([]*ShadowsocksURI, error) {
+	if len(data) < bundleHeaderSize+chacha20poly1305.Overhead || !bytes.HasPrefix(data, []byte(bundleMagic)) {
+		return nil, errors.New("invalid bundle")
+	}
+
+	if data[4] != bundleVersion {
+		return nil, errors.New("unsupported bundle version " + strconv.Itoa(int(data[4])))
+	}
+
+	if !validBundleCost(data[5], data[6], data[7]) {
+		return nil, errors.New("invalid bundle key derivation parameters")
+	}
+
+	header := data[:bundleHeaderSize]
+
+	aead, err := newBundleAEAD(header, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := header[8+bundleSaltSize:]
+
+	plaintext, err := aead.Open(nil, nonce, data[bundleHeaderSize:], header)
+	if err != nil {
+		return nil, ErrBundleDecrypt
+	}
+
+	return DecodeFormat(plaintext, "sip002")
+}
+
+// validBundleCost ... Reports whether the scrypt parameters of a bundle are
+// within the limits of OpenBundle.
+func validBundleCost(logN, r, p byte) bool {
+	if logN < 1 || logN > bundleMaxLogN || r < 1 || r > bundleMaxR || p < 1 || p > bundleMaxP {
+		return false
+	}
+
+	return 128*(1<<uint(logN))*int(r) <= bundleMaxMemory
+}
+
+// newBundleAEAD ... Derive the key from passphrase with the scrypt parameters and salt of header.
+func newBundleAEAD(header []byte, passphrase string) (cipher.AEAD, error) {
+	n := 1 << uint(header[5])
+	salt := header[8 : 8+bundleSaltSize]
+
+	key, err := scrypt.Key([]byte(passphrase), salt, n, int(header[6]), int(header[7]), chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, errors.New("invalid bundle key derivation parameters")
+	}
+
+	return chacha20poly1305.NewX(key)
+}
@@ -0,0 +1,68 @@
+package ss_test
+
+import (
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestBundle(t *testing.T) {
+	uris := newTestList()
+
+	sealed, err := ss.SealBundle(uris, "correct horse")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	opened, err := ss.OpenBundle(sealed, "correct horse")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(opened) != len(uris) {
+		t.Fatalf("Expected %d servers, Got: %d", len(uris), len(opened))
+	}
+
+	for i := range uris {
+		if !opened[i].Equal(uris[i]) {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v", i, uris[i], opened[i])
+		}
+	}
+
+	if _, err := ss.OpenBundle(sealed, "wrong horse"); err != ss.ErrBundleDecrypt {
+		t.Errorf("Expected ErrBundleDecrypt for wrong passphrase, Got: %v", err)
+	}
+
+	// Tampering with the header or the ciphertext is detected.
+	for _, i := range []int{7, 20, len(sealed) - 1} {
+		tampered := append([]byte{}, sealed...)
+		tampered[i] ^= 1
+
+		if _, err := ss.OpenBundle(tampered, "correct horse"); err == nil {
+			t.Errorf("Expected error for modified byte %d", i)
+		}
+	}
+
+	unsupported := append([]byte{}, sealed...)
+	unsupported[4] = 2
+
+	for _, data := range [][]byte{nil, []byte("SSUB"), sealed[:40], unsupported} {
+		if _, err := ss.OpenBundle(data, "correct horse"); err == nil || err == ss.ErrBundleDecrypt {
+			t.Errorf("Expected invalid bundle error, Got: %v", err)
+		}
+	}
+
+	// Costly scrypt parameters are rejected before deriving the key.
+	for i, cost := range [][3]byte{{20, 32, 1}, {21, 1, 1}, {18, 16, 1}, {15, 8, 5}, {0, 8, 1}, {15, 0, 1}} {
+		costly := append([]byte{}, sealed...)
+		copy(costly[5:8], cost[:])
+
+		if _, err := ss.OpenBundle(costly, "correct horse"); err == nil || err == ss.ErrBundleDecrypt {
+			t.Errorf("#%d test failed. Expected invalid parameters error, Got: %v", i, err)
+		}
+	}
+
+	if _, err := ss.SealBundle(uris, ""); err == nil {
+		t.Errorf("Expected error for empty passphrase")
+	}
+}
@@ -0,0 +1,248 @@
+package ss
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// SOCKS5 protocol constants, see RFC 1928.
+const (
+	socksVersion = 5
+
+	socksAuthNone         = 0
+	socksAuthUnacceptable = 0xFF
+
+	socksCmdConnect      = 1
+	socksCmdUDPAssociate = 3
+
+	socksReplySucceeded        = 0
+	socksReplyGeneralFailure   = 1
+	socksReplyCmdNotSupported  = 7
+	socksReplyAddrNotSupported = 8
+)
+
+// ShadowsocksClient ... Local SOCKS5 proxy tunneling TCP connections and, by
+// the Mode of the configuration, UDP datagrams through the shadowsocks server
+// of a client configuration, like sslocal. TCP goes through the SIP003 plugin
+// of the configuration if any, UDP always goes to the server directly.
+type ShadowsocksClient struct {
+	config    *ShadowsocksClientConfig
+	cipher    *AEADCipher
+	listeners listenerGroup
+
+	mu     sync.Mutex
+	plugin *PluginProcess // started by the first Serve
+}
+
+// NewShadowsocksClient ... Returns client of scc. Only AEAD methods are supported.
+func NewShadowsocksClient(scc *ShadowsocksClientConfig) (*ShadowsocksClient, error) {
+	cipher, err := NewAEADCipher(scc.Auth.Method(), scc.Auth.Password())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkMode(scc.Mode); err != nil {
+		return nil, err
+	}
+
+	if scc.Plugin != nil {
+		if err := scc.Plugin.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ShadowsocksClient{config: scc, cipher: cipher}, nil
+}
+
+// ListenAndServe ... Listen on the local address of the configuration and serve SOCKS5.
+func (c *ShadowsocksClient) ListenAndServe() error {
+	ln, err := net.Listen("tcp", dialAddress(c.config.Local))
+	if err != nil {
+		return err
+	}
+
+	return c.Serve(ln)
+}
+
+// Serve ... Serve SOCKS5 on ln until Close is called, ln is closed by Close.
+// The plugin of the configuration is started first.
+func (c *ShadowsocksClient) Serve(ln net.Listener) error {
+	if err := c.startPlugin(); err != nil {
+		ln.Close()
+		return err
+	}
+
+	return c.listeners.serve(ln, c.serveConn)
+}
+
+// startPlugin ... Start the plugin of the configuration unless already running.
+func (c *ShadowsocksClient) startPlugin() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.config.Plugin == nil || c.plugin != nil {
+		return nil
+	}
+
+	plugin, err := StartPlugin(c.config.Plugin, c.config.Remote)
+	if err != nil {
+		return err
+	}
+
+	if !c.listeners.add(plugin) {
+		return errClosed
+	}
+
+	c.plugin = plugin
+
+	return nil
+}
+
+// Close ... Stop accepting connections and stop the plugin, which ends the tunnels
+// through it. Tunnels without a plugin are not interrupted.
+func (c *ShadowsocksClient) Close() error {
+	return c.listeners.close()
+}
+
+// timeout ... Timeout of connections to the remote server.
+func (c *ShadowsocksClient) timeout() time.Duration {
+	if c.config.Timeout <= 0 {
+		return 300 * time.Second
+	}
+
+	return time.Duration(c.config.Timeout) * time.Second
+}
+
+// serveConn ... Handle one SOCKS5 connection.
+func (c *ShadowsocksClient) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(c.timeout()))
+
+	cmd, target, err := socksHandshake(conn)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case cmd == socksCmdConnect && relaysTCP(c.config.Mode):
+		c.connect(conn, target)
+	case cmd == socksCmdUDPAssociate && relaysUDP(c.config.Mode):
+		c.associate(conn)
+	default:
+		conn.Write(socksReply(socksReplyCmdNotSupported))
+	}
+}
+
+// connect ... Handle SOCKS5 CONNECT of conn to target.
+func (c *ShadowsocksClient) connect(conn net.Conn, target string) {
+	remote, err := c.dial(target)
+	if err != nil {
+		conn.Write(socksReply(socksReplyGeneralFailure))
+		return
+	}
+	defer remote.Close()
+
+	if _, err := conn.Write(socksReply(socksReplySucceeded)); err != nil {
+		return
+	}
+
+	conn.SetDeadline(time.Time{})
+	relay(conn, remote)
+}
+
+// dial ... Connect to target through the plugin or the server.
+func (c *ShadowsocksClient) dial(target string) (net.Conn, error) {
+	addr := dialAddress(c.config.Remote)
+	if c.plugin != nil {
+		addr = c.plugin.Addr()
+	}
+
+	return dialStream(c.cipher, addr, target, c.timeout())
+}
+
+// socksHandshake ... Negotiate no authentication and read a request, returns
+// its command and "host:port". Malformed requests are answered.
+func socksHandshake(conn net.Conn) (byte, string, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return 0, "", err
+	}
+
+	if header[0] != socksVersion {
+		return 0, "", errors.New("invalid SOCKS version")
+	}
+
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return 0, "", err
+	}
+
+	method := byte(socksAuthUnacceptable)
+	for _, m := range methods {
+		if m == socksAuthNone {
+			method = socksAuthNone
+		}
+	}
+
+	if _, err := conn.Write([]byte{socksVersion, method}); err != nil {
+		return 0, "", err
+	}
+
+	if method == socksAuthUnacceptable {
+		return 0, "", errors.New("no acceptable SOCKS authentication method")
+	}
+
+	var request [3]byte
+	if _, err := io.ReadFull(conn, request[:]); err != nil {
+		return 0, "", err
+	}
+
+	if request[0] != socksVersion {
+		return 0, "", errors.New("invalid SOCKS version")
+	}
+
+	target, err := ReadSocksAddr(conn)
+	if err != nil {
+		conn.Write(socksReply(socksReplyAddrNotSupported))
+		return 0, "", err
+	}
+
+	return request[1], target, nil
+}
+
+// socksReply ... Returns SOCKS5 reply with an unspecified bound address.
+func socksReply(code byte) []byte {
+	return []byte{socksVersion, code, 0, socksAddrIPv4, 0, 0, 0, 0, 0, 0}
+}
+
+// socksReplyAddr ... Returns SOCKS5 reply with bound address addr.
+func socksReplyAddr(code byte, addr string) ([]byte, error) {
+	return AppendSocksAddr([]byte{socksVersion, code, 0}, addr)
+}
+
+// relay ... Copy between a and b in both directions until both are done,
+// passing on half-closes.
+func relay(a, b net.Conn) {
+	done := make(chan struct{})
+
+	go func() {
+		io.Copy(b, a)
+		closeWrite(b)
+		close(done)
+	}()
+
+	io.Copy(a, b)
+	closeWrite(a)
+	<-done
+}
+
+// closeWrite ... Shut down the writing side of conn if supported.
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+	}
+}
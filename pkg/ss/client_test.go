@@ -0,0 +1,89 @@
+package ss_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+	"github.com/vgxbj/ssuri/pkg/ss/sstest"
+)
+
+// startClient ... Start SOCKS5 client of uri on a free local port, returns its address.
+func startClient(t *testing.T, uri *ss.ShadowsocksURI) string {
+	t.Helper()
+
+	client, err := ss.NewShadowsocksClient(ss.ToShadowsocksClientConfig(uri))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	go client.Serve(ln)
+	t.Cleanup(func() { client.Close() })
+
+	return ln.Addr().String()
+}
+
+func TestShadowsocksClient(t *testing.T) {
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte("hello " + string(body)))
+	}))
+	defer web.Close()
+
+	server := sstest.NewServer("aes-256-gcm", "correct horse")
+	defer server.Close()
+
+	proxy := startClient(t, server.URI)
+	proxyURL, _ := url.Parse("socks5://" + proxy)
+
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	// By IP address, and by hostname resolved on the server.
+	targets := []string{web.URL, strings.Replace(web.URL, "127.0.0.1", "localhost", 1)}
+	large := strings.Repeat("x", 100000)
+
+	for _, target := range targets {
+		resp, err := client.Post(target, "text/plain", strings.NewReader(large))
+		if err != nil {
+			t.Errorf("%v", err)
+			continue
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if string(body) != "hello "+large {
+			t.Errorf("Unexpected body of %d bytes", len(body))
+		}
+	}
+
+	// UDP ASSOCIATE is refused in the default tcp_only mode.
+	conn, err := net.Dial("tcp", proxy)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte{5, 1, 0, 5, 3, 0, 1, 127, 0, 0, 1, 0, 53})
+
+	reply := make([]byte, 12)
+	if _, err := io.ReadFull(conn, reply); err != nil || reply[1] != 0 || reply[3] != 7 {
+		t.Errorf("Expected command not supported, Got: %v, %v", reply, err)
+	}
+
+	uri := &ss.ShadowsocksURI{Remote: ss.NewServer("127.0.0.1", 8388), Auth: ss.NewAuthInfo("rc4-md5", "test")}
+	if _, err := ss.NewShadowsocksClient(ss.ToShadowsocksClientConfig(uri)); err == nil {
+		t.Errorf("Expected error for stream method")
+	}
+}
@@ -1,6 +1,10 @@
 package ss
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
 
 // ShadowsocksClientConfig ... Struct for shadowsocks client configuration.
 // See: https://github.com/shadowsocks/shadowsocks/wiki/Configuration-via-Config-File
@@ -24,6 +28,21 @@ type ShadowsocksClientConfig struct {
 	FastOpen bool        // Fast open, default by false
 	Workers  int         // Available on Unix/Linux, default by 1
 	Plugin   *PluginInfo // Plugin, only used in defined in SIP003
+	Tag      string      // Name of server, "remarks" in JSON, optional
+
+	Mode       string // tcp_only, udp_only or tcp_and_udp, optional
+	Nameserver string // DNS server used by the client, optional
+
+	// Unknown JSON fields (e.g. ipv6_first, acl) kept verbatim by DecodeJSON
+	// and written back by EncodeClientJSON.
+	Extras map[string]json.RawMessage
+}
+
+// clientJSONKeys ... JSON keys understood by DecodeJSON, "tag" is an alias of "remarks".
+var clientJSONKeys = []string{
+	"server", "server_port", "local_address", "local_port", "password", "timeout",
+	"method", "fast_open", "workers", "plugin", "plugin_opts", "remarks", "tag",
+	"mode", "nameserver",
 }
 
 // ShadowsocksClientJSON ... Shadowsocks client configuration in JSON format.
@@ -39,6 +58,9 @@ type ShadowsocksClientJSON struct {
 	Workers      int    `json:"workers"`
 	Plugin       string `json:"plugin"`
 	PluginOpts   string `json:"plugin_opts"`
+	Remarks      string `json:"remarks,omitempty"`
+	Mode         string `json:"mode,omitempty"`
+	Nameserver   string `json:"nameserver,omitempty"`
 }
 
 // NewShadowsocksClientJSON ... Generate new client configuration in JSON format.
@@ -62,6 +84,9 @@ func NewShadowsocksClientJSON(scc *ShadowsocksClientConfig) *ShadowsocksClientJS
 		Workers:      scc.Workers,
 		Plugin:       pluginName,
 		PluginOpts:   pluginOpts,
+		Remarks:      scc.Tag,
+		Mode:         scc.Mode,
+		Nameserver:   scc.Nameserver,
 	}
 }
 
@@ -80,6 +105,9 @@ func EncodeClientJSON(scc *ShadowsocksClientConfig, legacy bool) ([]byte, error)
 			Method       string `json:"method"`
 			FastOpen     bool   `json:"fast_open"`
 			Workers      int    `json:"workers"`
+			Remarks      string `json:"remarks,omitempty"`
+			Mode         string `json:"mode,omitempty"`
+			Nameserver   string `json:"nameserver,omitempty"`
 		}{
 			Server:       clientJSON.Server,
 			ServerPort:   clientJSON.ServerPort,
@@ -90,19 +118,97 @@ func EncodeClientJSON(scc *ShadowsocksClientConfig, legacy bool) ([]byte, error)
 			Method:       clientJSON.Method,
 			FastOpen:     clientJSON.FastOpen,
 			Workers:      clientJSON.Workers,
+			Remarks:      clientJSON.Remarks,
+			Mode:         clientJSON.Mode,
+			Nameserver:   clientJSON.Nameserver,
 		}, "", "    ")
+		if err != nil {
+			return nil, err
+		}
 
-		return data, err
+		return appendJSONExtras(data, scc.Extras)
 	}
 
 	data, err := json.MarshalIndent(clientJSON, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	return appendJSONExtras(data, scc.Extras)
+}
+
+// appendJSONExtras ... Append extra fields, sorted by key, to an indented JSON object.
+func appendJSONExtras(data []byte, extras map[string]json.RawMessage) ([]byte, error) {
+	if len(extras) == 0 {
+		return data, nil
+	}
+
+	keys := make([]string, 0, len(extras))
+	for k := range extras {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+
+	buf.Write(bytes.TrimSuffix(bytes.TrimSpace(data), []byte("}")))
+
+	for _, k := range keys {
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.WriteString(",")
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(extras[k])
+	}
+
+	buf.WriteString("}")
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, buf.Bytes(), "", "    "); err != nil {
+		return nil, err
+	}
 
-	return data, err
+	return indented.Bytes(), nil
 }
 
 // DecodeJSON ... Decode JSON to ShadowsocksClietnConfig.
+// Optional fields missing from JSON are set to DefaultClientOptions(), missing
+// required fields (server, server_port, method, password) are reported all at
+// once by a *ValidationError. Comments and trailing commas are accepted.
 func DecodeJSON(data []byte) (*ShadowsocksClientConfig, error) {
-	var clientJSON ShadowsocksClientJSON
+	return DecodeJSONWithOptions(data, nil)
+}
+
+// DecodeJSONWithOptions ... Decode JSON to ShadowsocksClietnConfig, taking
+// optional fields missing from JSON from opts. A nil opts means
+// DefaultClientOptions(). See DecodeJSON().
+func DecodeJSONWithOptions(data []byte, opts *ClientOptions) (*ShadowsocksClientConfig, error) {
+	if opts == nil {
+		opts = DefaultClientOptions()
+	}
+
+	data = relaxJSON(data)
+
+	var clientJSON struct {
+		ShadowsocksClientJSON
+		Tag string `json:"tag"`
+	}
+
+	// Unmarshal only overwrites fields present in JSON.
+	clientJSON.ShadowsocksClientJSON = ShadowsocksClientJSON{
+		LocalAddress: opts.Local.Hostname(),
+		LocalPort:    opts.Local.Port(),
+		Timeout:      opts.Timeout,
+		FastOpen:     opts.FastOpen,
+		Workers:      opts.Workers,
+		Mode:         opts.Mode,
+		Nameserver:   opts.Nameserver,
+	}
 
 	err := json.Unmarshal(data, &clientJSON)
 
@@ -110,14 +216,32 @@ func DecodeJSON(data []byte) (*ShadowsocksClientConfig, error) {
 		return nil, err
 	}
 
-	opts, err := ParsePluginOpts(clientJSON.PluginOpts)
+	if err := validateClientJSON(&clientJSON.ShadowsocksClientJSON); err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for _, k := range clientJSONKeys {
+		delete(fields, k)
+	}
+
+	tag := clientJSON.Remarks
+	if tag == "" {
+		tag = clientJSON.Tag
+	}
+
+	pluginOpts, err := ParsePluginOpts(clientJSON.PluginOpts)
 	if err != nil {
 		return nil, err
 	}
 
 	var plugin *PluginInfo
-	if len(opts) != 0 {
-		plugin = NewPlugin(clientJSON.Plugin, opts)
+	if clientJSON.Plugin != "" || len(pluginOpts) != 0 {
+		plugin = NewPlugin(clientJSON.Plugin, pluginOpts)
 	}
 
 	scc := &ShadowsocksClientConfig{
@@ -128,17 +252,31 @@ func DecodeJSON(data []byte) (*ShadowsocksClientConfig, error) {
 		FastOpen: clientJSON.FastOpen,
 		Workers:  clientJSON.Workers,
 		Plugin:   plugin,
+		Tag:      tag,
+
+		Mode:       clientJSON.Mode,
+		Nameserver: clientJSON.Nameserver,
+	}
+
+	if len(fields) != 0 {
+		scc.Extras = fields
 	}
 
 	return scc, nil
 }
 
 // ToShadowsocksURI ... Convert JSON configuration to shadowsocks URI.
+// The tag falls back to the remote address if the configuration has none.
 func ToShadowsocksURI(scc *ShadowsocksClientConfig) *ShadowsocksURI {
+	tag := scc.Tag
+	if tag == "" {
+		tag = scc.Remote.String()
+	}
+
 	return &ShadowsocksURI{
 		Remote: scc.Remote,
 		Auth:   scc.Auth,
-		Tag:    scc.Remote.String(),
+		Tag:    tag,
 		Plugin: scc.Plugin,
 	}
 }
@@ -0,0 +1,67 @@
+package ss_test
+
+import (
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestClientJSONRoundTrip(t *testing.T) {
+	data := `{
+    "server": "some_host",
+    "server_port": 8118,
+    "local_address": "127.0.0.1",
+    "local_port": 1080,
+    "password": "test",
+    "timeout": 300,
+    "method": "bf-cfb",
+    "fast_open": false,
+    "workers": 1,
+    "plugin": "v2ray-plugin",
+    "plugin_opts": "",
+    "remarks": "example-server",
+    "nameserver": "8.8.8.8",
+    "mode": "tcp_and_udp",
+    "ipv6_first": true,
+    "acl": {"bypass": ["10.0.0.0/8"]}
+}`
+
+	expected := "{\n    \"server\": \"some_host\",\n    \"server_port\": 8118,\n    \"local_address\": \"127.0.0.1\",\n    \"local_port\": 1080,\n    \"password\": \"test\",\n    \"timeout\": 300,\n    \"method\": \"bf-cfb\",\n    \"fast_open\": false,\n    \"workers\": 1,\n    \"plugin\": \"v2ray-plugin\",\n    \"plugin_opts\": \"\",\n    \"remarks\": \"example-server\",\n    \"mode\": \"tcp_and_udp\",\n    \"nameserver\": \"8.8.8.8\",\n" +
+		"    \"acl\": {\n        \"bypass\": [\n            \"10.0.0.0/8\"\n        ]\n    },\n    \"ipv6_first\": true\n}"
+
+	cc, err := ss.DecodeJSON([]byte(data))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if cc.Tag != "example-server" || len(cc.Extras) != 2 || cc.Mode != "tcp_and_udp" || cc.Plugin == nil || cc.Plugin.Name() != "v2ray-plugin" {
+		t.Fatalf("Unexpected config: %v", *cc)
+	}
+
+	encoded, err := ss.EncodeClientJSON(cc, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if string(encoded) != expected {
+		t.Errorf("Expected:\n%v\nGot:\n%v", expected, string(encoded))
+	}
+
+	uri := ss.ToShadowsocksURI(cc)
+	if uri.Tag != "example-server" {
+		t.Errorf("Expected tag to be kept, Got: %v", uri.Tag)
+	}
+
+	if ss.ToShadowsocksClientConfig(uri).Tag != "example-server" {
+		t.Errorf("Expected tag to survive JSON -> URI -> JSON")
+	}
+
+	aliased, err := ss.DecodeJSON([]byte(`{"server": "some_host", "server_port": 8118, "method": "bf-cfb", "password": "test", "tag": "t"}`))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if aliased.Tag != "t" || aliased.Extras != nil {
+		t.Errorf("Expected \"tag\" to be read as remarks, Got: %v", *aliased)
+	}
+}
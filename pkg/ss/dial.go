@@ -0,0 +1,62 @@
+package ss
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// Dial ... Connect to target "host:port" through the shadowsocks server of uri.
+// Only AEAD methods are supported. A plugin is started for the connection
+// alone and stopped when it is closed.
+func Dial(uri *ShadowsocksURI, target string, timeout time.Duration) (net.Conn, error) {
+	c, err := NewAEADCipher(uri.Auth.Method(), uri.Auth.Password())
+	if err != nil {
+		return nil, err
+	}
+
+	if uri.Plugin == nil {
+		return dialStream(c, dialAddress(uri.Remote), target, timeout)
+	}
+
+	plugin, err := StartPlugin(uri.Plugin, uri.Remote)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialStream(c, plugin.Addr(), target, timeout)
+	if err != nil {
+		plugin.Close()
+		return nil, err
+	}
+
+	return &pluginConn{conn, plugin}, nil
+}
+
+// dialStream ... Connect to target through the shadowsocks server at addr.
+func dialStream(c *AEADCipher, addr, target string, timeout time.Duration) (net.Conn, error) {
+	header, err := AppendSocksAddr(nil, target)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	conn = c.StreamConn(conn)
+
+	// The target address leads the first chunk of the stream.
+	if _, err := conn.Write(header); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// dialAddress ... Returns "host:port" of server for net.Dial, IPv6 hosts in brackets.
+func dialAddress(s *Server) string {
+	return net.JoinHostPort(normalizeHostname(s.hostname), strconv.Itoa(s.port))
+}
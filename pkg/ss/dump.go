@@ -0,0 +1,176 @@
+package ss
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// DumpFormats ... Supported formats of EncodeDump.
+var DumpFormats = []string{"text", "json", "yaml", "csv", "tsv"}
+
+// dumpColumns ... Column names of tabular dump formats.
+var dumpColumns = []string{"tag", "hostname", "port", "method", "password", "plugin", "plugin_opts"}
+
+// DumpRecord ... Flat description of a server used by dump formats.
+type DumpRecord struct {
+	Tag        string            `json:"tag"`
+	Hostname   string            `json:"hostname"`
+	Port       int               `json:"port"`
+	Method     string            `json:"method"`
+	Password   string            `json:"password"`
+	Plugin     string            `json:"plugin"`
+	PluginOpts map[string]string `json:"plugin_opts"`
+}
+
+// NewDumpRecord ... Returns the dump record of shadowsocks URI.
+func NewDumpRecord(uri *ShadowsocksURI) *DumpRecord {
+	record := &DumpRecord{
+		Tag:        uri.Tag,
+		Hostname:   uri.Remote.Hostname(),
+		Port:       uri.Remote.Port(),
+		Method:     uri.Auth.Method(),
+		Password:   uri.Auth.Password(),
+		PluginOpts: map[string]string{},
+	}
+
+	if uri.Plugin != nil {
+		record.Plugin = uri.Plugin.Name()
+
+		for k, v := range uri.Plugin.Options() {
+			record.PluginOpts[k] = v
+		}
+	}
+
+	return record
+}
+
+// pluginOptsString ... Encode plugin options as <key>=<value>;...
+func (record *DumpRecord) pluginOptsString() string {
+	return NewPlugin(record.Plugin, record.PluginOpts).OptionsString()
+}
+
+// row ... Returns record as a row of dumpColumns.
+func (record *DumpRecord) row() []string {
+	return []string{
+		record.Tag,
+		record.Hostname,
+		strconv.Itoa(record.Port),
+		record.Method,
+		record.Password,
+		record.Plugin,
+		record.pluginOptsString(),
+	}
+}
+
+// EncodeDump ... Write a dump of servers to w in one of DumpFormats.
+// Tabular formats write a header and one row per server, JSON and YAML write
+// a list even for a single server.
+func EncodeDump(w io.Writer, uris []*ShadowsocksURI, format string) error {
+	records := make([]*DumpRecord, len(uris))
+	for i, uri := range uris {
+		records[i] = NewDumpRecord(uri)
+	}
+
+	switch format {
+	case "text":
+		return encodeDumpText(w, records)
+	case "json":
+		data, err := json.MarshalIndent(records, "", "    ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", data)
+
+		return err
+	case "yaml":
+		return encodeDumpYAML(w, records)
+	case "csv", "tsv":
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+
+		cw.Write(dumpColumns)
+		for _, record := range records {
+			cw.Write(record.row())
+		}
+
+		cw.Flush()
+
+		return cw.Error()
+	}
+
+	return errors.New("invalid dump format: " + format)
+}
+
+// encodeDumpText ... Write human readable dump.
+func encodeDumpText(w io.Writer, records []*DumpRecord) error {
+	for _, record := range records {
+		name := record.Tag
+		if name == "" {
+			name = NewServer(record.Hostname, record.Port).String()
+		}
+
+		fmt.Fprintf(w, "Server #%s:\n", name)
+		fmt.Fprintf(w, "Hostname          : %v\n", record.Hostname)
+		fmt.Fprintf(w, "Port              : %v\n", record.Port)
+		fmt.Fprintf(w, "Encryption Method : %v\n", record.Method)
+		fmt.Fprintf(w, "Password          : %v\n", record.Password)
+
+		if record.Plugin != "" {
+			fmt.Fprintf(w, "Plugin            : %v\n", record.Plugin)
+			fmt.Fprintf(w, "Plugin Options    : %v\n", record.pluginOptsString())
+		}
+
+		if _, err := fmt.Fprintf(w, "\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeDumpYAML ... Write records as a YAML sequence.
+// Strings are emitted as JSON strings, which YAML accepts as double-quoted scalars.
+func encodeDumpYAML(w io.Writer, records []*DumpRecord) error {
+	if len(records) == 0 {
+		_, err := fmt.Fprintf(w, "[]\n")
+		return err
+	}
+
+	for _, record := range records {
+		fmt.Fprintf(w, "- tag: %s\n", yamlString(record.Tag))
+		fmt.Fprintf(w, "  hostname: %s\n", yamlString(record.Hostname))
+		fmt.Fprintf(w, "  port: %d\n", record.Port)
+		fmt.Fprintf(w, "  method: %s\n", yamlString(record.Method))
+		fmt.Fprintf(w, "  password: %s\n", yamlString(record.Password))
+		fmt.Fprintf(w, "  plugin: %s\n", yamlString(record.Plugin))
+
+		if len(record.PluginOpts) == 0 {
+			fmt.Fprintf(w, "  plugin_opts: {}\n")
+			continue
+		}
+
+		fmt.Fprintf(w, "  plugin_opts:\n")
+
+		plugin := NewPlugin(record.Plugin, record.PluginOpts)
+		for _, k := range plugin.optionKeys() {
+			if _, err := fmt.Fprintf(w, "    %s: %s\n", yamlString(k), yamlString(record.PluginOpts[k])); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// yamlString ... Quote s as a YAML double-quoted scalar.
+func yamlString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
@@ -0,0 +1,83 @@
+package ss_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestEncodeDump(t *testing.T) {
+	uris := []*ss.ShadowsocksURI{
+		{
+			Remote: ss.NewServer("192.168.100.1", 8888),
+			Auth:   ss.NewAuthInfo("rc4-md5", "pass,\"wd"),
+			Tag:    "example-server",
+			Plugin: ss.NewPlugin("obfs-local", map[string]string{"obfs-host": "a.com", "obfs": "http"}),
+		},
+		{
+			Remote: ss.NewServer("::1", 8388),
+			Auth:   ss.NewAuthInfo("bf-cfb", "test"),
+		},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			"text",
+			"Server #example-server:\nHostname          : 192.168.100.1\nPort              : 8888\nEncryption Method : rc4-md5\nPassword          : pass,\"wd\nPlugin            : obfs-local\nPlugin Options    : obfs=http;obfs-host=a.com\n\n" +
+				"Server #[::1]:8388:\nHostname          : ::1\nPort              : 8388\nEncryption Method : bf-cfb\nPassword          : test\n\n",
+		},
+		{
+			"csv",
+			"tag,hostname,port,method,password,plugin,plugin_opts\n" +
+				"example-server,192.168.100.1,8888,rc4-md5,\"pass,\"\"wd\",obfs-local,obfs=http;obfs-host=a.com\n" +
+				",::1,8388,bf-cfb,test,,\n",
+		},
+		{
+			"tsv",
+			"tag\thostname\tport\tmethod\tpassword\tplugin\tplugin_opts\n" +
+				"example-server\t192.168.100.1\t8888\trc4-md5\t\"pass,\"\"wd\"\tobfs-local\tobfs=http;obfs-host=a.com\n" +
+				"\t::1\t8388\tbf-cfb\ttest\t\t\n",
+		},
+		{
+			"yaml",
+			"- tag: \"example-server\"\n  hostname: \"192.168.100.1\"\n  port: 8888\n  method: \"rc4-md5\"\n  password: \"pass,\\\"wd\"\n  plugin: \"obfs-local\"\n  plugin_opts:\n    \"obfs\": \"http\"\n    \"obfs-host\": \"a.com\"\n" +
+				"- tag: \"\"\n  hostname: \"::1\"\n  port: 8388\n  method: \"bf-cfb\"\n  password: \"test\"\n  plugin: \"\"\n  plugin_opts: {}\n",
+		},
+	}
+
+	for i, ut := range tests {
+		var buf bytes.Buffer
+
+		if err := ss.EncodeDump(&buf, uris, ut.format); err != nil {
+			t.Errorf("#%d test failed. %v", i, err)
+			continue
+		}
+
+		if buf.String() != ut.expected {
+			t.Errorf("#%d test failed. Expected:\n%v\nGot:\n%v", i, ut.expected, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ss.EncodeDump(&buf, uris, "json"); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var records []ss.DumpRecord
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(records) != 2 || records[0].Password != "pass,\"wd" || records[0].PluginOpts["obfs-host"] != "a.com" {
+		t.Errorf("Unexpected JSON dump: %v", buf.String())
+	}
+
+	if err := ss.EncodeDump(&buf, uris, "xml"); err == nil {
+		t.Errorf("Expected error for unknown format")
+	}
+}
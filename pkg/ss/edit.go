@@ -0,0 +1,168 @@
+package ss
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// pluginOptionPrefix ... Prefix of edit fields naming a single plugin option.
+const pluginOptionPrefix = "plugin.opt."
+
+// Edit ... Changes fields of a server in place.
+type Edit func(uri *ShadowsocksURI) error
+
+// ParseEdit ... Parse edit from <field>=<value>. Fields are host, port,
+// method, password, tag, plugin ("<name>[;<options>]" replacing the plugin,
+// "" to remove it), plugin_opts (all options) and plugin.opt.<key> (one
+// option, "" for a flag). "plugin.opt.<key>!" removes an option.
+func ParseEdit(spec string) (Edit, error) {
+	if strings.HasPrefix(spec, pluginOptionPrefix) && strings.HasSuffix(spec, "!") {
+		key := strings.TrimSuffix(strings.TrimPrefix(spec, pluginOptionPrefix), "!")
+		if key == "" || strings.Contains(key, "=") {
+			return nil, errors.New("invalid edit: " + spec)
+		}
+
+		return func(uri *ShadowsocksURI) error {
+			if uri.Plugin != nil {
+				uri.Plugin.DeleteOption(key)
+			}
+
+			return nil
+		}, nil
+	}
+
+	splitIndex := strings.IndexByte(spec, '=')
+	if splitIndex <= 0 {
+		return nil, errors.New("invalid edit: " + spec)
+	}
+
+	field, value := spec[:splitIndex], spec[splitIndex+1:]
+
+	switch field {
+	case "host":
+		if _, err := parseRemoteServer(NewServer(value, 1).String()); err != nil || strings.ContainsAny(value, "/?#@ \t") {
+			return nil, errors.New("invalid host: " + value)
+		}
+
+		return func(uri *ShadowsocksURI) error {
+			uri.Remote.SetHostname(value)
+			return nil
+		}, nil
+	case "port":
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, errors.New("invalid port: " + value)
+		}
+
+		return func(uri *ShadowsocksURI) error {
+			uri.Remote.SetPort(port)
+			return nil
+		}, nil
+	case "method":
+		if value == "" {
+			return nil, errors.New("empty method")
+		}
+
+		return func(uri *ShadowsocksURI) error {
+			uri.Auth.SetMethod(value)
+			return nil
+		}, nil
+	case "password":
+		if value == "" {
+			return nil, errors.New("empty password")
+		}
+
+		return func(uri *ShadowsocksURI) error {
+			uri.Auth.SetPassword(value)
+			return nil
+		}, nil
+	case "tag":
+		return func(uri *ShadowsocksURI) error {
+			uri.Tag = value
+			return nil
+		}, nil
+	case "plugin":
+		plugin, err := parsePlugin(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(uri *ShadowsocksURI) error {
+			uri.Plugin = nil
+			if plugin != nil {
+				uri.Plugin = NewPlugin(plugin.name, copyOptions(plugin.options))
+			}
+
+			return nil
+		}, nil
+	case "plugin_opts":
+		options, err := ParsePluginOpts(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(uri *ShadowsocksURI) error {
+			if uri.Plugin == nil {
+				return errors.New("no plugin to set plugin_opts of")
+			}
+
+			uri.Plugin = NewPlugin(uri.Plugin.name, copyOptions(options))
+
+			return nil
+		}, nil
+	}
+
+	key := strings.TrimPrefix(field, pluginOptionPrefix)
+	if key == field || key == "" {
+		return nil, errors.New("invalid edit field: " + field)
+	}
+
+	return func(uri *ShadowsocksURI) error {
+		if uri.Plugin == nil {
+			return errors.New("no plugin to set option " + key + " of")
+		}
+
+		uri.Plugin.SetOption(key, value)
+
+		return nil
+	}, nil
+}
+
+// copyOptions ... Returns a copy of plugin options.
+func copyOptions(options map[string]string) map[string]string {
+	c := make(map[string]string, len(options))
+	for k, v := range options {
+		c[k] = v
+	}
+
+	return c
+}
+
+// ApplyEdits ... Returns a copy of uri with edits applied in order. Values
+// are checked by ParseEdit, fields not edited are kept as they are.
+func ApplyEdits(uri *ShadowsocksURI, edits ...Edit) (*ShadowsocksURI, error) {
+	c := uri.Copy()
+
+	for _, edit := range edits {
+		if err := edit(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// ApplyClientEdits ... Returns a copy of scc with edits applied in order to
+// its server, keeping local settings, mode and unknown JSON fields.
+func ApplyClientEdits(scc *ShadowsocksClientConfig, edits ...Edit) (*ShadowsocksClientConfig, error) {
+	uri, err := ApplyEdits(&ShadowsocksURI{Remote: scc.Remote, Auth: scc.Auth, Tag: scc.Tag, Plugin: scc.Plugin}, edits...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *scc
+	c.Remote, c.Auth, c.Tag, c.Plugin = uri.Remote, uri.Auth, uri.Tag, uri.Plugin
+
+	return &c, nil
+}
@@ -0,0 +1,189 @@
+package ss_test
+
+import (
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestApplyEdits(t *testing.T) {
+	original := &ss.ShadowsocksURI{
+		Remote: ss.NewServer("jp1.example.com", 443),
+		Auth:   ss.NewAuthInfo("aes-256-gcm", "old"),
+		Tag:    "JP 01",
+		Plugin: ss.NewPlugin("obfs-local", map[string]string{"obfs": "http"}),
+	}
+
+	tests := []struct {
+		edits    []string
+		expected string // SIP002 URI, "" if applying fails
+	}{
+		{[]string{"password=new;p@ss", "port=8443"},
+			"ss://YWVzLTI1Ni1nY206bmV3O3BAc3M=@jp1.example.com:8443/?plugin=obfs-local%3Bobfs%3Dhttp#JP%2001"},
+		{[]string{"host=::1", "method=chacha20-ietf-poly1305", "tag=Tokyo #2"},
+			"ss://Y2hhY2hhMjAtaWV0Zi1wb2x5MTMwNTpvbGQ=@[::1]:443/?plugin=obfs-local%3Bobfs%3Dhttp#Tokyo%20%232"},
+		{[]string{"plugin.opt.obfs-host=a.com", "plugin.opt.obfs=tls"},
+			"ss://YWVzLTI1Ni1nY206b2xk@jp1.example.com:443/?plugin=obfs-local%3Bobfs%3Dtls%3Bobfs-host%3Da.com#JP%2001"},
+		{[]string{"plugin=v2ray-plugin;mode=websocket", "plugin.opt.tls="},
+			"ss://YWVzLTI1Ni1nY206b2xk@jp1.example.com:443/?plugin=v2ray-plugin%3Bmode%3Dwebsocket%3Btls#JP%2001"},
+		{[]string{"plugin_opts=obfs=tls", "plugin.opt.obfs-host=a.com", "plugin.opt.obfs-host!"},
+			"ss://YWVzLTI1Ni1nY206b2xk@jp1.example.com:443/?plugin=obfs-local%3Bobfs%3Dtls#JP%2001"},
+		{[]string{"plugin=simple-obfs;mode=tls"},
+			"ss://YWVzLTI1Ni1nY206b2xk@jp1.example.com:443/?plugin=simple-obfs%3Bmode%3Dtls#JP%2001"},
+		{[]string{"plugin="},
+			"ss://YWVzLTI1Ni1nY206b2xk@jp1.example.com:443#JP%2001"},
+		{[]string{"plugin=", "plugin.opt.obfs=http"}, ""},
+	}
+
+	for i, ut := range tests {
+		edits := []ss.Edit{}
+
+		for _, spec := range ut.edits {
+			edit, err := ss.ParseEdit(spec)
+			if err != nil {
+				t.Fatalf("#%d test failed. ParseEdit(%q) failed: %v", i, spec, err)
+			}
+
+			edits = append(edits, edit)
+		}
+
+		edited, err := ss.ApplyEdits(original, edits...)
+
+		if ut.expected == "" {
+			if err == nil {
+				t.Errorf("#%d test failed. Expected error, Got: %v", i, edited)
+			}
+
+			continue
+		}
+
+		if err != nil || edited.EncodeSIP002URI() != ut.expected {
+			t.Errorf("#%d test failed.\nExpected: %s\nGot     : %v, %v", i, ut.expected, edited, err)
+		}
+	}
+
+	if original.Auth.Password() != "old" || original.Plugin.Options()["obfs"] != "http" || len(original.Plugin.Options()) != 1 {
+		t.Errorf("Original server was modified: %v", original)
+	}
+}
+
+func TestApplyClientEdits(t *testing.T) {
+	data := []byte(`{
+    "server": "a.com",
+    "server_port": 8388,
+    "local_address": "0.0.0.0",
+    "local_port": 1081,
+    "password": "old",
+    "timeout": 60,
+    "method": "aes-256-gcm",
+    "mode": "tcp_and_udp",
+    "ipv6_first": true
+}`)
+
+	configs, err := ss.DecodeClientJSONList(data)
+	if err != nil || len(configs) != 1 {
+		t.Fatalf("DecodeClientJSONList() failed: %v, %v", configs, err)
+	}
+
+	edit, _ := ss.ParseEdit("password=new")
+
+	scc, err := ss.ApplyClientEdits(configs[0], edit)
+	if err != nil {
+		t.Fatalf("ApplyClientEdits() failed: %v", err)
+	}
+
+	encoded, err := ss.EncodeClientJSONList([]*ss.ShadowsocksClientConfig{scc}, false)
+	if err != nil {
+		t.Fatalf("EncodeClientJSONList() failed: %v", err)
+	}
+
+	expected := `{
+    "server": "a.com",
+    "server_port": 8388,
+    "local_address": "0.0.0.0",
+    "local_port": 1081,
+    "password": "new",
+    "timeout": 60,
+    "method": "aes-256-gcm",
+    "fast_open": false,
+    "workers": 1,
+    "plugin": "",
+    "plugin_opts": "",
+    "mode": "tcp_and_udp",
+    "ipv6_first": true
+}
+`
+
+	if string(encoded) != expected {
+		t.Errorf("Expected: %s\nGot     : %s", expected, encoded)
+	}
+
+	if configs[0].Auth.Password() != "old" {
+		t.Errorf("Original configuration was modified: %v", configs[0])
+	}
+
+	// Arrays stay arrays.
+	encoded, err = ss.EncodeClientJSONList([]*ss.ShadowsocksClientConfig{scc}, true)
+	if err != nil || !ss.IsClientJSONArray(encoded) {
+		t.Errorf("Expected array, Got: %s, %v", encoded, err)
+	}
+
+	configs, err = ss.DecodeClientJSONList(encoded)
+	if err != nil || len(configs) != 1 || configs[0].Local.Port() != 1081 || configs[0].Extras["ipv6_first"] == nil {
+		t.Errorf("Array round trip failed: %v, %v", configs, err)
+	}
+}
+
+func TestParseEditInvalid(t *testing.T) {
+	for i, spec := range []string{
+		"", "=a", "port", "port=0", "port=65536", "port=http", "host=", "host=a/b", "host=a:b", "host=[::1]",
+		"method=", "password=", "plugin=;a=b", "plugin_opts=\\", "plugin.opt.=a", "plugin.opt.!", "user=root",
+	} {
+		if _, err := ss.ParseEdit(spec); err == nil {
+			t.Errorf("#%d test failed. Expected error for %q", i, spec)
+		}
+	}
+}
+
+func TestURIFlavor(t *testing.T) {
+	uri := &ss.ShadowsocksURI{
+		Remote: ss.NewServer("192.168.100.1", 8888),
+		Auth:   ss.NewAuthInfo("bf-cfb", "test"),
+	}
+
+	tagged := uri.Copy()
+	tagged.Tag = "example-server"
+
+	plugged := tagged.Copy()
+	plugged.Plugin = ss.NewPlugin("obfs-local", map[string]string{"obfs": "http"})
+
+	tests := []struct {
+		uri      *ss.ShadowsocksURI
+		flavor   string
+		expected string
+		detected string // Flavor actually written
+	}{
+		{uri, ss.FlavorPlain, "ss://bf-cfb:test@192.168.100.1:8888", ss.FlavorPlain},
+		{tagged, ss.FlavorPlain, "ss://bf-cfb:test@192.168.100.1:8888#example-server", ss.FlavorPlain},
+		{plugged, ss.FlavorPlain, "ss://YmYtY2ZiOnRlc3Q=@192.168.100.1:8888/?plugin=obfs-local%3Bobfs%3Dhttp#example-server", ss.FlavorSIP002},
+		{tagged, ss.FlavorLegacy, "ss://YmYtY2ZiOnRlc3RAMTkyLjE2OC4xMDAuMTo4ODg4#example-server", ss.FlavorLegacy},
+		{plugged, ss.FlavorLegacy, "ss://YmYtY2ZiOnRlc3Q=@192.168.100.1:8888/?plugin=obfs-local%3Bobfs%3Dhttp#example-server", ss.FlavorSIP002},
+		{tagged, ss.FlavorSIP002, "ss://YmYtY2ZiOnRlc3Q=@192.168.100.1:8888#example-server", ss.FlavorSIP002},
+		{tagged, "unknown", "ss://YmYtY2ZiOnRlc3Q=@192.168.100.1:8888#example-server", ss.FlavorSIP002},
+	}
+
+	for i, ut := range tests {
+		encoded := ut.uri.EncodeFlavor(ut.flavor)
+		if encoded != ut.expected {
+			t.Errorf("#%d test failed.\nExpected: %s\nGot     : %s", i, ut.expected, encoded)
+		}
+
+		if flavor := ss.DetectURIFlavor(encoded); flavor != ut.detected {
+			t.Errorf("#%d test failed. Expected flavor %s, Got: %s", i, ut.detected, flavor)
+		}
+	}
+
+	if flavor := ss.DetectURIFlavor("http://example.com"); flavor != "" {
+		t.Errorf("Expected no flavor, Got: %s", flavor)
+	}
+}
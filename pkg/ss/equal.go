@@ -0,0 +1,146 @@
+package ss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Equal ... Reports whether two servers have the same hostname and port.
+func (s *Server) Equal(other *Server) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+
+	return s.hostname == other.hostname && s.port == other.port
+}
+
+// Equal ... Reports whether two authentication information are identical.
+func (auth *AuthInfo) Equal(other *AuthInfo) bool {
+	if auth == nil || other == nil {
+		return auth == other
+	}
+
+	return auth.method == other.method && auth.password == other.password
+}
+
+// Equal ... Reports whether two plugins have the same name and options.
+func (plugin *PluginInfo) Equal(other *PluginInfo) bool {
+	if plugin == nil || other == nil {
+		return plugin == other
+	}
+
+	if plugin.name != other.name || len(plugin.options) != len(other.options) {
+		return false
+	}
+
+	for k, v := range plugin.options {
+		if ov, ok := other.options[k]; !ok || ov != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Equal ... Reports whether two shadowsocks URIs are identical field by field.
+// Use Normalize() on both sides first to ignore cosmetic differences.
+func (uri *ShadowsocksURI) Equal(other *ShadowsocksURI) bool {
+	if uri == nil || other == nil {
+		return uri == other
+	}
+
+	return uri.Remote.Equal(other.Remote) &&
+		uri.Auth.Equal(other.Auth) &&
+		uri.Tag == other.Tag &&
+		uri.Plugin.Equal(other.Plugin)
+}
+
+// Copy ... Returns a deep copy of shadowsocks URI.
+func (uri *ShadowsocksURI) Copy() *ShadowsocksURI {
+	if uri == nil {
+		return nil
+	}
+
+	c := &ShadowsocksURI{Tag: uri.Tag}
+
+	if uri.Remote != nil {
+		c.Remote = NewServer(uri.Remote.hostname, uri.Remote.port)
+	}
+
+	if uri.Auth != nil {
+		c.Auth = NewAuthInfo(uri.Auth.method, uri.Auth.password)
+	}
+
+	if uri.Plugin != nil {
+		c.Plugin = NewPlugin(uri.Plugin.name, copyOptions(uri.Plugin.options))
+	}
+
+	return c
+}
+
+// Normalize ... Returns a canonical copy of shadowsocks URI.
+// Hostnames are lower-cased and stripped of IPv6 brackets, IP addresses are
+// written in their shortest form, and methods are lower-cased. An empty plugin
+// is dropped. Plugin options are always encoded in sorted order.
+func (uri *ShadowsocksURI) Normalize() *ShadowsocksURI {
+	n := uri.Copy()
+	if n == nil {
+		return nil
+	}
+
+	if n.Remote != nil {
+		n.Remote.hostname = normalizeHostname(n.Remote.hostname)
+	}
+
+	if n.Auth != nil {
+		n.Auth.method = strings.ToLower(strings.TrimSpace(n.Auth.method))
+	}
+
+	if n.Plugin != nil && n.Plugin.name == "" && len(n.Plugin.options) == 0 {
+		n.Plugin = nil
+	}
+
+	return n
+}
+
+// Fingerprint ... Returns a stable hash of the server identity.
+// Two URIs share a fingerprint iff their normalized hostname, port, method and
+// password are equal. Tag and plugin do not take part in the identity.
+func (uri *ShadowsocksURI) Fingerprint() string {
+	n := uri.Normalize()
+
+	var hostname, method, password string
+	var port int
+
+	if n.Remote != nil {
+		hostname, port = n.Remote.hostname, n.Remote.port
+	}
+
+	if n.Auth != nil {
+		method, password = n.Auth.method, n.Auth.password
+	}
+
+	h := sha256.New()
+
+	// Length-prefix each field so that no two identities collide.
+	for _, field := range []string{hostname, strconv.Itoa(port), method, password} {
+		h.Write([]byte(strconv.Itoa(len(field)) + ":" + field))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeHostname ... Canonicalize hostname.
+func normalizeHostname(hostname string) string {
+	hostname = strings.TrimSpace(hostname)
+	hostname = strings.TrimSuffix(strings.TrimPrefix(hostname, "["), "]")
+
+	if ip := net.ParseIP(hostname); ip != nil {
+		return ip.String()
+	}
+
+	return strings.ToLower(strings.TrimSuffix(hostname, "."))
+}
@@ -0,0 +1,154 @@
+package ss_test
+
+import (
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestEqual(t *testing.T) {
+	base := &ss.ShadowsocksURI{
+		Remote: ss.NewServer("192.168.100.1", 8888),
+		Auth:   ss.NewAuthInfo("bf-cfb", "test"),
+		Tag:    "example-server",
+		Plugin: ss.NewPlugin("obfs-local", map[string]string{"obfs": "http", "obfs-host": "a.com"}),
+	}
+
+	change := func(f func(uri *ss.ShadowsocksURI)) *ss.ShadowsocksURI {
+		c := base.Copy()
+		f(c)
+
+		return c
+	}
+
+	tests := []struct {
+		uri1     *ss.ShadowsocksURI
+		uri2     *ss.ShadowsocksURI
+		expected bool
+	}{
+		{base, base, true},
+		{base, base.Copy(), true},
+		{nil, nil, true},
+		{base, nil, false},
+		{nil, base, false},
+		{base, change(func(uri *ss.ShadowsocksURI) { uri.Remote = ss.NewServer("192.168.100.2", 8888) }), false},
+		{base, change(func(uri *ss.ShadowsocksURI) { uri.Remote = ss.NewServer("192.168.100.1", 8889) }), false},
+		{base, change(func(uri *ss.ShadowsocksURI) { uri.Remote = nil }), false},
+		{base, change(func(uri *ss.ShadowsocksURI) { uri.Auth = ss.NewAuthInfo("BF-CFB", "test") }), false},
+		{base, change(func(uri *ss.ShadowsocksURI) { uri.Auth = ss.NewAuthInfo("bf-cfb", "Test") }), false},
+		{base, change(func(uri *ss.ShadowsocksURI) { uri.Auth = nil }), false},
+		{base, change(func(uri *ss.ShadowsocksURI) { uri.Tag = "" }), false},
+		{base, change(func(uri *ss.ShadowsocksURI) { uri.Plugin = nil }), false},
+		{base, change(func(uri *ss.ShadowsocksURI) {
+			uri.Plugin = ss.NewPlugin("simple-obfs", map[string]string{"obfs": "http", "obfs-host": "a.com"})
+		}), false},
+		{base, change(func(uri *ss.ShadowsocksURI) {
+			uri.Plugin = ss.NewPlugin("obfs-local", map[string]string{"obfs": "tls", "obfs-host": "a.com"})
+		}), false},
+		{base, change(func(uri *ss.ShadowsocksURI) {
+			uri.Plugin = ss.NewPlugin("obfs-local", map[string]string{"obfs": "http", "obfs-uri": "a.com"})
+		}), false},
+		{base, change(func(uri *ss.ShadowsocksURI) {
+			uri.Plugin = ss.NewPlugin("obfs-local", map[string]string{"obfs": "http"})
+		}), false},
+		{
+			&ss.ShadowsocksURI{Plugin: ss.NewPlugin("kcptun", nil)},
+			&ss.ShadowsocksURI{Plugin: ss.NewPlugin("kcptun", map[string]string{})},
+			true,
+		},
+	}
+
+	for i, ut := range tests {
+		if ut.uri1.Equal(ut.uri2) != ut.expected || ut.uri2.Equal(ut.uri1) != ut.expected {
+			t.Errorf("#%d test failed. Expected: %v\n%v\n%v", i, ut.expected, ut.uri1, ut.uri2)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		uri      ss.ShadowsocksURI
+		expected ss.ShadowsocksURI
+	}{
+		{
+			ss.ShadowsocksURI{
+				Remote: ss.NewServer("Test.Example.COM", 8888),
+				Auth:   ss.NewAuthInfo("AES-256-GCM", "Passwd"),
+				Tag:    "example-server",
+			},
+			ss.ShadowsocksURI{
+				Remote: ss.NewServer("test.example.com", 8888),
+				Auth:   ss.NewAuthInfo("aes-256-gcm", "Passwd"),
+				Tag:    "example-server",
+			},
+		},
+		{
+			ss.ShadowsocksURI{
+				Remote: ss.NewServer("[2001:DB8:0:0:0:0:0:1]", 8388),
+				Auth:   ss.NewAuthInfo("rc4-md5", "passwd"),
+				Plugin: ss.NewPlugin("", nil),
+			},
+			ss.ShadowsocksURI{
+				Remote: ss.NewServer("2001:db8::1", 8388),
+				Auth:   ss.NewAuthInfo("rc4-md5", "passwd"),
+			},
+		},
+		{
+			ss.ShadowsocksURI{
+				Remote: ss.NewServer("::ffff:192.168.100.1", 8388),
+				Auth:   ss.NewAuthInfo("bf-cfb", "test"),
+				Plugin: ss.NewPlugin("obfs-local", map[string]string{"obfs-host": "a.com", "obfs": "http"}),
+			},
+			ss.ShadowsocksURI{
+				Remote: ss.NewServer("192.168.100.1", 8388),
+				Auth:   ss.NewAuthInfo("bf-cfb", "test"),
+				Plugin: ss.NewPlugin("obfs-local", map[string]string{"obfs": "http", "obfs-host": "a.com"}),
+			},
+		},
+	}
+
+	for i, ut := range tests {
+		n := ut.uri.Normalize()
+
+		if !n.Equal(&ut.expected) {
+			t.Errorf("#%d test failed.\nExpected: %v\nGot     : %v", i, ut.expected, *n)
+		}
+
+		if n.Fingerprint() != ut.uri.Fingerprint() {
+			t.Errorf("#%d test failed. Fingerprint changed after Normalize()", i)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := &ss.ShadowsocksURI{
+		Remote: ss.NewServer("192.168.100.1", 8888),
+		Auth:   ss.NewAuthInfo("bf-cfb", "test"),
+		Tag:    "example-server",
+	}
+
+	retagged := base.Copy()
+	retagged.Tag = "another-server"
+	retagged.Plugin = ss.NewPlugin("obfs-local", map[string]string{"obfs": "http"})
+
+	if base.Fingerprint() != retagged.Fingerprint() {
+		t.Errorf("tag and plugin should not affect fingerprint")
+	}
+
+	if base.Equal(retagged) {
+		t.Errorf("Equal() should compare tag and plugin")
+	}
+
+	changed := []*ss.ShadowsocksURI{
+		{Remote: ss.NewServer("192.168.100.2", 8888), Auth: ss.NewAuthInfo("bf-cfb", "test")},
+		{Remote: ss.NewServer("192.168.100.1", 8889), Auth: ss.NewAuthInfo("bf-cfb", "test")},
+		{Remote: ss.NewServer("192.168.100.1", 8888), Auth: ss.NewAuthInfo("rc4-md5", "test")},
+		{Remote: ss.NewServer("192.168.100.1", 8888), Auth: ss.NewAuthInfo("bf-cfb", "Test")},
+	}
+
+	for i, c := range changed {
+		if c.Fingerprint() == base.Fingerprint() {
+			t.Errorf("#%d test failed. Fingerprint should differ", i)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package ss
+
+import "net"
+
+// Unexported functions for the external tests of package ss_test.
+var (
+	ParsePlugin  = parsePlugin
+	EncodePlugin = (*PluginInfo).encode
+
+	UnregisterFormat = unregisterFormat
+)
+
+// WriteEmptyChunk ... Write a chunk without payload to conn returned by
+// StreamConn, which Write never does but peers may.
+func WriteEmptyChunk(conn net.Conn) error {
+	c := conn.(*aeadConn)
+
+	// Sends the salt first if needed.
+	if _, err := c.Write(nil); err != nil {
+		return err
+	}
+
+	buf := c.writer.Seal(nil, c.wNonce, []byte{0, 0}, nil)
+	incrementNonce(c.wNonce)
+	buf = c.writer.Seal(buf, c.wNonce, nil, nil)
+	incrementNonce(c.wNonce)
+
+	_, err := c.Conn.Write(buf)
+
+	return err
+}
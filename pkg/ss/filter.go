@@ -0,0 +1,161 @@
+package ss
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Filter ... Predicate selecting servers from a list.
+type Filter func(uri *ShadowsocksURI) bool
+
+// FilterTag ... Select servers whose tag matches re.
+func FilterTag(re *regexp.Regexp) Filter {
+	return func(uri *ShadowsocksURI) bool {
+		return re.MatchString(uri.Tag)
+	}
+}
+
+// FilterHost ... Select servers whose hostname matches a shell pattern, e.g. "*.example.com".
+// Hostnames are compared in normalized form.
+func FilterHost(pattern string) Filter {
+	pattern = normalizeHostname(pattern)
+
+	return func(uri *ShadowsocksURI) bool {
+		ok, _ := path.Match(pattern, normalizeHostname(uri.Remote.Hostname()))
+		return ok
+	}
+}
+
+// FilterPortRange ... Select servers whose port is within [min, max].
+func FilterPortRange(min, max int) Filter {
+	return func(uri *ShadowsocksURI) bool {
+		return uri.Remote.Port() >= min && uri.Remote.Port() <= max
+	}
+}
+
+// FilterMethod ... Select servers using method, case-insensitively.
+func FilterMethod(method string) Filter {
+	return func(uri *ShadowsocksURI) bool {
+		return strings.EqualFold(uri.Auth.Method(), method)
+	}
+}
+
+// FilterPlugin ... Select servers using plugin name. An empty name selects servers without plugin.
+func FilterPlugin(name string) Filter {
+	return func(uri *ShadowsocksURI) bool {
+		if uri.Plugin == nil {
+			return name == ""
+		}
+
+		return uri.Plugin.Name() == name
+	}
+}
+
+// Not ... Negate filter.
+func Not(f Filter) Filter {
+	return func(uri *ShadowsocksURI) bool {
+		return !f(uri)
+	}
+}
+
+// ParseFilter ... Parse filter from <field>=<value> or <field>!=<value>.
+// Fields are tag (regexp), host (shell pattern), port (<port> or <min>-<max>),
+// method and plugin ("none" for servers without plugin).
+func ParseFilter(spec string) (Filter, error) {
+	splitIndex := strings.IndexByte(spec, '=')
+	if splitIndex <= 0 {
+		return nil, errors.New("invalid filter: " + spec)
+	}
+
+	field, value := spec[:splitIndex], spec[splitIndex+1:]
+
+	negate := strings.HasSuffix(field, "!")
+	field = strings.TrimSuffix(field, "!")
+
+	var f Filter
+
+	switch field {
+	case "tag":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+
+		f = FilterTag(re)
+	case "host":
+		if _, err := path.Match(value, ""); err != nil {
+			return nil, err
+		}
+
+		f = FilterHost(value)
+	case "port":
+		min, max, err := parsePortRange(value)
+		if err != nil {
+			return nil, err
+		}
+
+		f = FilterPortRange(min, max)
+	case "method":
+		f = FilterMethod(value)
+	case "plugin":
+		if value == "none" {
+			value = ""
+		}
+
+		f = FilterPlugin(value)
+	default:
+		return nil, errors.New("invalid filter field: " + field)
+	}
+
+	if negate {
+		return Not(f), nil
+	}
+
+	return f, nil
+}
+
+// Select ... Returns servers matching all filters, in their original order.
+func Select(uris []*ShadowsocksURI, filters ...Filter) []*ShadowsocksURI {
+	selected := []*ShadowsocksURI{}
+
+	for _, uri := range uris {
+		ok := true
+
+		for _, f := range filters {
+			if !f(uri) {
+				ok = false
+				break
+			}
+		}
+
+		if ok {
+			selected = append(selected, uri)
+		}
+	}
+
+	return selected
+}
+
+// parsePortRange ... Parse <port> or <min>-<max>.
+func parsePortRange(s string) (int, int, error) {
+	bounds := strings.SplitN(s, "-", 2)
+
+	min, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return 0, 0, errors.New("invalid <port>")
+	}
+
+	if len(bounds) == 1 {
+		return min, min, nil
+	}
+
+	max, err := strconv.Atoi(bounds[1])
+	if err != nil || max < min {
+		return 0, 0, errors.New("invalid <port>")
+	}
+
+	return min, max, nil
+}
@@ -0,0 +1,176 @@
+package ss_test
+
+import (
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func newTestList() []*ss.ShadowsocksURI {
+	return []*ss.ShadowsocksURI{
+		{Remote: ss.NewServer("hk1.example.com", 8388), Auth: ss.NewAuthInfo("aes-256-gcm", "a"), Tag: "HK-01"},
+		{Remote: ss.NewServer("jp1.example.com", 443), Auth: ss.NewAuthInfo("rc4-md5", "b"), Tag: "\U0001F1EF\U0001F1F5 Tokyo",
+			Plugin: ss.NewPlugin("obfs-local", map[string]string{"obfs": "http"})},
+		{Remote: ss.NewServer("192.168.100.1", 8888), Auth: ss.NewAuthInfo("bf-cfb", "c"), Tag: "us west"},
+		{Remote: ss.NewServer("hk2.example.com", 8389), Auth: ss.NewAuthInfo("AES-256-GCM", "d"), Tag: "HK-02"},
+	}
+}
+
+func tagsOf(uris []*ss.ShadowsocksURI) []string {
+	tags := []string{}
+
+	for _, uri := range uris {
+		tags = append(tags, uri.Tag)
+	}
+
+	return tags
+}
+
+func equalStrings(s1, s2 []string) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+
+	for i := range s1 {
+		if s1[i] != s2[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []string
+	}{
+		{"tag=^HK", []string{"HK-01", "HK-02"}},
+		{"tag!=^HK", []string{"\U0001F1EF\U0001F1F5 Tokyo", "us west"}},
+		{"host=*.EXAMPLE.com", []string{"HK-01", "\U0001F1EF\U0001F1F5 Tokyo", "HK-02"}},
+		{"port=8000-8388", []string{"HK-01"}},
+		{"port=443", []string{"\U0001F1EF\U0001F1F5 Tokyo"}},
+		{"method=aes-256-gcm", []string{"HK-01", "HK-02"}},
+		{"plugin=obfs-local", []string{"\U0001F1EF\U0001F1F5 Tokyo"}},
+		{"plugin=none", []string{"HK-01", "us west", "HK-02"}},
+	}
+
+	for i, ut := range tests {
+		f, err := ss.ParseFilter(ut.spec)
+		if err != nil {
+			t.Errorf("#%d test failed. %v", i, err)
+			continue
+		}
+
+		if got := tagsOf(ss.Select(newTestList(), f)); !equalStrings(got, ut.expected) {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v", i, ut.expected, got)
+		}
+	}
+
+	for _, spec := range []string{"", "tag", "=x", "tag=(", "port=b", "port=9-1", "foo=bar", "host=["} {
+		if _, err := ss.ParseFilter(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	tests := []struct {
+		fields   []string
+		expected []string
+	}{
+		{[]string{"port"}, []string{"\U0001F1EF\U0001F1F5 Tokyo", "HK-01", "HK-02", "us west"}},
+		{[]string{"-port"}, []string{"us west", "HK-02", "HK-01", "\U0001F1EF\U0001F1F5 Tokyo"}},
+		{[]string{"method", "-tag"}, []string{"HK-02", "HK-01", "us west", "\U0001F1EF\U0001F1F5 Tokyo"}},
+		{[]string{"plugin"}, []string{"HK-01", "us west", "HK-02", "\U0001F1EF\U0001F1F5 Tokyo"}},
+	}
+
+	for i, ut := range tests {
+		uris := newTestList()
+
+		if err := ss.SortBy(uris, ut.fields...); err != nil {
+			t.Errorf("#%d test failed. %v", i, err)
+			continue
+		}
+
+		if got := tagsOf(uris); !equalStrings(got, ut.expected) {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v", i, ut.expected, got)
+		}
+	}
+
+	if err := ss.SortBy(newTestList(), "country"); err == nil {
+		t.Errorf("Expected error for unknown field")
+	}
+}
+
+func TestRename(t *testing.T) {
+	uris := newTestList()
+
+	if err := ss.Rename(uris, "{{.Country}}-{{.Index}}"); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	expected := []string{"HK-1", "JP-2", "US-3", "HK-4"}
+	if got := tagsOf(uris); !equalStrings(got, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, got)
+	}
+
+	uris = newTestList()
+	if err := ss.Rename(uris, "{{.Missing}}"); err == nil {
+		t.Errorf("Expected error for unknown field")
+	}
+
+	if uris[0].Tag != "HK-01" {
+		t.Errorf("Failed Rename() should not modify tags")
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{"{{.Country}}-{{.Index}}", true},
+		{"{{.Tag}} {{.Hostname}}:{{.Port}} {{.Method}} {{.Plugin}}", true},
+		{"fixed", true},
+		{"{{.Tga}}", false},
+		{"{{.Missing}}", false},
+		{"{{.Index", false},
+		{"{{.Tag.Name}}", false},
+	}
+
+	for i, ut := range tests {
+		if err := ss.CheckTemplate(ut.text); (err == nil) != ut.expected {
+			t.Errorf("#%d test failed. Expected valid: %v, Got: %v", i, ut.expected, err)
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	uris := newTestList()
+
+	re, err := ss.ParseFilter("host=*.example.com")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	pipeline := ss.Pipeline{
+		ss.FilterStage(re),
+		ss.SortStage("-port"),
+		ss.RenameStage("{{.Country}}{{.Index}}:{{.Port}}"),
+	}
+
+	out, err := pipeline.Run(uris)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	expected := []string{"HK1:8389", "HK2:8388", "JP3:443"}
+	if got := tagsOf(out); !equalStrings(got, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, got)
+	}
+
+	if uris[0].Tag != "HK-01" {
+		t.Errorf("Pipeline should not modify inputs")
+	}
+}
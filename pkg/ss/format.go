@@ -0,0 +1,418 @@
+package ss
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Decoder ... Decodes servers from data in some format.
+type Decoder interface {
+	Decode(data []byte) ([]*ShadowsocksURI, error)
+}
+
+// Encoder ... Encodes servers into some format.
+type Encoder interface {
+	Encode(uris []*ShadowsocksURI) ([]byte, error)
+}
+
+// DecoderFunc ... Adapter to use an ordinary function as Decoder.
+type DecoderFunc func(data []byte) ([]*ShadowsocksURI, error)
+
+// Decode ... Implements Decoder.
+func (f DecoderFunc) Decode(data []byte) ([]*ShadowsocksURI, error) {
+	return f(data)
+}
+
+// EncoderFunc ... Adapter to use an ordinary function as Encoder.
+type EncoderFunc func(uris []*ShadowsocksURI) ([]byte, error)
+
+// Encode ... Implements Encoder.
+func (f EncoderFunc) Encode(uris []*ShadowsocksURI) ([]byte, error) {
+	return f(uris)
+}
+
+// Format ... A named format of server lists. Either Decoder or Encoder may be
+// nil for formats that can only be read or only be written.
+type Format struct {
+	Name        string
+	Description string
+	Decoder     Decoder
+	Encoder     Encoder
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = make(map[string]*Format)
+)
+
+// RegisterFormat ... Make a format available by its name.
+// Like database/sql drivers, it panics if the name is already registered or
+// the format has neither Decoder nor Encoder.
+func RegisterFormat(f *Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	if f == nil || f.Name == "" || (f.Decoder == nil && f.Encoder == nil) {
+		panic("ss: RegisterFormat format is invalid")
+	}
+
+	if _, dup := formats[f.Name]; dup {
+		panic("ss: RegisterFormat called twice for format " + f.Name)
+	}
+
+	formats[f.Name] = f
+}
+
+// unregisterFormat ... Remove format of name, for tests registering formats.
+func unregisterFormat(name string) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	delete(formats, name)
+}
+
+// LookupFormat ... Returns the registered format of name.
+func LookupFormat(name string) (*Format, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	f, ok := formats[name]
+
+	return f, ok
+}
+
+// Formats ... Returns sorted names of registered formats.
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// DecoderFormats ... Returns sorted names of formats that can be decoded.
+func DecoderFormats() []string {
+	names := []string{}
+
+	for _, name := range Formats() {
+		if f, _ := LookupFormat(name); f.Decoder != nil {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// EncoderFormats ... Returns sorted names of formats that can be encoded.
+func EncoderFormats() []string {
+	names := []string{}
+
+	for _, name := range Formats() {
+		if f, _ := LookupFormat(name); f.Encoder != nil {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// DecodeFormat ... Decode data in the registered format from.
+func DecodeFormat(data []byte, from string) ([]*ShadowsocksURI, error) {
+	f, ok := LookupFormat(from)
+	if !ok || f.Decoder == nil {
+		return nil, errors.New("invalid input format: " + from)
+	}
+
+	return f.Decoder.Decode(data)
+}
+
+// DecodeOptions ... Options of DecodeFormatWithOptions.
+type DecodeOptions struct {
+	// StrictPlugins rejects servers with options their plugin does not
+	// accept, see PluginInfo.Validate. Unknown plugins are not checked.
+	StrictPlugins bool
+}
+
+// DecodeFormatWithOptions ... Decode data in the registered format from,
+// checking the servers as opts asks for. Nil opts decodes like DecodeFormat.
+func DecodeFormatWithOptions(data []byte, from string, opts *DecodeOptions) ([]*ShadowsocksURI, error) {
+	uris, err := DecodeFormat(data, from)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts != nil && opts.StrictPlugins {
+		if err := validatePlugins(uris); err != nil {
+			return nil, err
+		}
+	}
+
+	return uris, nil
+}
+
+// validatePlugins ... Returns an error listing the plugin problem of every
+// server, nil if there is none.
+func validatePlugins(uris []*ShadowsocksURI) error {
+	problems := []string{}
+
+	for i, uri := range uris {
+		if uri.Plugin == nil {
+			continue
+		}
+
+		if err := uri.Plugin.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("server #%d: %v", i+1, err))
+		}
+	}
+
+	if len(problems) != 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// EncodeFormat ... Encode servers in the registered format to.
+func EncodeFormat(uris []*ShadowsocksURI, to string) ([]byte, error) {
+	f, ok := LookupFormat(to)
+	if !ok || f.Encoder == nil {
+		return nil, errors.New("invalid output format: " + to)
+	}
+
+	return f.Encoder.Encode(uris)
+}
+
+// Convert ... Convert data between any two registered formats.
+func Convert(data []byte, from, to string) ([]byte, error) {
+	if f, ok := LookupFormat(to); !ok || f.Encoder == nil {
+		return nil, errors.New("invalid output format: " + to)
+	}
+
+	uris, err := DecodeFormat(data, from)
+	if err != nil {
+		return nil, err
+	}
+
+	return EncodeFormat(uris, to)
+}
+
+func init() {
+	RegisterFormat(&Format{
+		Name:        "uri",
+		Description: "shadowsocks URIs of any scheme, one per line, or a base64 subscription",
+		Decoder: DecoderFunc(func(data []byte) ([]*ShadowsocksURI, error) {
+			return DecodeSubscription(string(data))
+		}),
+	})
+
+	RegisterFormat(&Format{
+		Name:        "sip002",
+		Description: "SIP002 URIs, one per line",
+		Decoder:     uriLinesDecoder(DecodeSIP002URI),
+		Encoder:     uriLinesEncoder((*ShadowsocksURI).EncodeSIP002URI),
+	})
+
+	RegisterFormat(&Format{
+		Name:        "legacy",
+		Description: "legacy base64 encoded URIs, one per line",
+		Decoder:     uriLinesDecoder(DecodeBase64URI),
+		Encoder:     uriLinesEncoder((*ShadowsocksURI).EncodeBase64URI),
+	})
+
+	RegisterFormat(&Format{
+		Name:        "plain",
+		Description: "plain URIs, one per line",
+		Decoder:     uriLinesDecoder(DecodePlainURI),
+		Encoder:     uriLinesEncoder((*ShadowsocksURI).EncodePlainURI),
+	})
+
+	RegisterFormat(&Format{
+		Name:        "subscription",
+		Description: "base64 encoded list of SIP002 URIs",
+		Decoder: DecoderFunc(func(data []byte) ([]*ShadowsocksURI, error) {
+			return DecodeSubscription(string(data))
+		}),
+		Encoder: EncoderFunc(func(uris []*ShadowsocksURI) ([]byte, error) {
+			return []byte(EncodeSubscription(uris) + "\n"), nil
+		}),
+	})
+
+	RegisterFormat(&Format{
+		Name:        "sip008",
+		Description: "SIP008 online configuration JSON document",
+		Decoder:     DecoderFunc(DecodeSIP008),
+		Encoder:     EncoderFunc(EncodeSIP008),
+	})
+
+	RegisterFormat(&Format{
+		Name:        "client-json",
+		Description: "shadowsocks client JSON configuration, an array for more than one server",
+		Decoder:     DecoderFunc(decodeClientJSONList),
+		Encoder:     NewClientJSONEncoder(nil),
+	})
+}
+
+// uriLinesDecoder ... Decoder of one URI per line using decode.
+func uriLinesDecoder(decode func(uri string) (*ShadowsocksURI, error)) Decoder {
+	return DecoderFunc(func(data []byte) ([]*ShadowsocksURI, error) {
+		uris := []*ShadowsocksURI{}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			uri, err := decode(line)
+			if err != nil {
+				return nil, err
+			}
+
+			uris = append(uris, uri)
+		}
+
+		return uris, nil
+	})
+}
+
+// uriLinesEncoder ... Encoder of one URI per line using encode.
+func uriLinesEncoder(encode func(uri *ShadowsocksURI) string) Encoder {
+	return EncoderFunc(func(uris []*ShadowsocksURI) ([]byte, error) {
+		var buf bytes.Buffer
+
+		for _, uri := range uris {
+			buf.WriteString(encode(uri) + "\n")
+		}
+
+		return buf.Bytes(), nil
+	})
+}
+
+// decodeClientJSONList ... Decode a JSON configuration or an array of them.
+func decodeClientJSONList(data []byte) ([]*ShadowsocksURI, error) {
+	configs, err := DecodeClientJSONList(data)
+	if err != nil {
+		return nil, err
+	}
+
+	uris := make([]*ShadowsocksURI, len(configs))
+	for i, scc := range configs {
+		uris[i] = ToShadowsocksURI(scc)
+	}
+
+	return uris, nil
+}
+
+// IsClientJSONArray ... Reports whether data holds an array of JSON client
+// configurations rather than a single one.
+func IsClientJSONArray(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("["))
+}
+
+// DecodeClientJSONList ... Decode a JSON client configuration, or an array of
+// them, keeping all settings. See DecodeJSON().
+func DecodeClientJSONList(data []byte) ([]*ShadowsocksClientConfig, error) {
+	if !IsClientJSONArray(data) {
+		scc, err := DecodeJSON(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*ShadowsocksClientConfig{scc}, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(bytes.TrimSpace(data), &items); err != nil {
+		return nil, err
+	}
+
+	configs := []*ShadowsocksClientConfig{}
+
+	for _, item := range items {
+		scc, err := DecodeJSON(item)
+		if err != nil {
+			return nil, err
+		}
+
+		configs = append(configs, scc)
+	}
+
+	return configs, nil
+}
+
+// EncodeClientJSONList ... Encode JSON client configurations, as an array if
+// array is true, otherwise the only one. Unknown fields are written back.
+func EncodeClientJSONList(configs []*ShadowsocksClientConfig, array bool) ([]byte, error) {
+	if !array {
+		if len(configs) != 1 {
+			return nil, errors.New("not exactly one client configuration")
+		}
+
+		data, err := EncodeClientJSON(configs[0], false)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(data, '\n'), nil
+	}
+
+	items := make([]json.RawMessage, len(configs))
+
+	for i, scc := range configs {
+		data, err := EncodeClientJSON(scc, false)
+		if err != nil {
+			return nil, err
+		}
+
+		items[i] = data
+	}
+
+	data, err := json.MarshalIndent(items, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// NewClientJSONEncoder ... Encoder of one server as JSON client configuration,
+// or more as an array. A nil opts means DefaultClientOptions().
+func NewClientJSONEncoder(opts *ClientOptions) Encoder {
+	if opts == nil {
+		opts = DefaultClientOptions()
+	}
+
+	return EncoderFunc(func(uris []*ShadowsocksURI) ([]byte, error) {
+		if len(uris) == 1 {
+			data, err := EncodeClientJSON(ToShadowsocksClientConfigWithOptions(uris[0], opts), false)
+			if err != nil {
+				return nil, err
+			}
+
+			return append(data, '\n'), nil
+		}
+
+		items := make([]*ShadowsocksClientJSON, len(uris))
+		for i, uri := range uris {
+			items[i] = NewShadowsocksClientJSON(ToShadowsocksClientConfigWithOptions(uri, opts))
+		}
+
+		data, err := json.MarshalIndent(items, "", "    ")
+		if err != nil {
+			return nil, err
+		}
+
+		return append(data, '\n'), nil
+	})
+}
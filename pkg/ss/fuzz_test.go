@@ -0,0 +1,218 @@
+package ss_test
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+// uriSeeds ... URIs of every scheme seeding the decoder fuzzers.
+var uriSeeds = []string{
+	"ss://YmYtY2ZiOnRlc3RAMTkyLjE2OC4xMDAuMTo4ODg4#example-server",
+	"ss://bf-cfb:test@192.168.100.1:8888",
+	"ss://YmYtY2ZiOnRlc3Q=@192.168.100.1:8888#example-server",
+	"ss://cmM0LW1kNTpwYXNzd2Q=@192.168.100.1:8888/?plugin=obfs-local%3Bobfs%3Dhttp#example-server",
+	"ss://YWVzLTI1Ni1nY206dGVzdA@[::1]:8388/?plugin=v2ray-plugin%3Btls%3Bhost%3Da.com#%F0%9F%87%AF%F0%9F%87%B5%20Tokyo",
+	"ss://aes-256-gcm:p@ss#w:rd@[2001:db8::1]:443#a#b",
+	"ss://bTpw@[fe80::1%25eth0]:1",
+}
+
+// checkURIRoundTrip ... Fail unless decoding the encoding of uri gives uri back.
+func checkURIRoundTrip(t *testing.T, uri *ss.ShadowsocksURI, encode func(*ss.ShadowsocksURI) string, decode func(string) (*ss.ShadowsocksURI, error)) {
+	t.Helper()
+
+	encoded := encode(uri)
+
+	decoded, err := decode(encoded)
+	if err != nil {
+		t.Fatalf("Decoding %q of %v failed: %v", encoded, uri, err)
+	}
+
+	if !decoded.Equal(uri) {
+		t.Fatalf("Round trip of %q mismatched.\nExpected: %v\nGot     : %v", encoded, uri, decoded)
+	}
+}
+
+// withoutTag ... Plain URIs are encoded without tag.
+func withoutTag(uri *ss.ShadowsocksURI) *ss.ShadowsocksURI {
+	c := uri.Copy()
+	c.Tag = ""
+
+	return c
+}
+
+func FuzzDecodeSIP002URI(f *testing.F) {
+	for _, seed := range uriSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		uri, err := ss.DecodeSIP002URI(s)
+		if err != nil {
+			return
+		}
+
+		checkURIRoundTrip(t, uri, (*ss.ShadowsocksURI).EncodeSIP002URI, ss.DecodeSIP002URI)
+	})
+}
+
+func FuzzDecodeBase64URI(f *testing.F) {
+	for _, seed := range uriSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		uri, err := ss.DecodeBase64URI(s)
+		if err != nil {
+			return
+		}
+
+		checkURIRoundTrip(t, uri, (*ss.ShadowsocksURI).EncodeBase64URI, ss.DecodeBase64URI)
+	})
+}
+
+func FuzzDecodePlainURI(f *testing.F) {
+	for _, seed := range uriSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		uri, err := ss.DecodePlainURI(s)
+		if err != nil {
+			return
+		}
+
+		checkURIRoundTrip(t, withoutTag(uri), (*ss.ShadowsocksURI).EncodePlainURI, ss.DecodePlainURI)
+	})
+}
+
+func FuzzParsePlugin(f *testing.F) {
+	for _, seed := range []string{
+		"obfs-local;obfs=http;obfs-host=a.com",
+		"v2ray-plugin;tls;host=a.com;path=/ws?ed=2048",
+		"ck-client;UID=abc=;PublicKey=a\\;b",
+		"my-plugin",
+		"my\\;plugin;k\\=ey=va\\\\lue;;",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		plugin, err := ss.ParsePlugin(s)
+		if err != nil || plugin == nil {
+			return
+		}
+
+		encoded := ss.EncodePlugin(plugin)
+
+		decoded, err := ss.ParsePlugin(encoded)
+		if err != nil || !decoded.Equal(plugin) {
+			t.Fatalf("Round trip of %q as %q mismatched: %v, %v", s, encoded, decoded, err)
+		}
+	})
+}
+
+func FuzzDecodeJSON(f *testing.F) {
+	for _, seed := range []string{
+		`{"server": "a.com", "server_port": 8388, "method": "aes-256-gcm", "password": "p;a=ss"}`,
+		`{"server": "::1", "server_port": 443, "password": "p", "method": "bf-cfb", "plugin": "obfs-local", "plugin_opts": "obfs=tls;obfs-host=a.com", "remarks": "x", "mode": "tcp_and_udp"}`,
+		`{"server": "a.com", "server_port": 1, "password": "p", "method": "m", /* comment */ "plugin_opts": "a=b",}`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		scc, err := ss.DecodeJSON(data)
+		if err != nil {
+			return
+		}
+
+		encoded, err := ss.EncodeClientJSON(scc, false)
+		if err != nil {
+			t.Fatalf("Encoding %v failed: %v", scc, err)
+		}
+
+		decoded, err := ss.DecodeJSON(encoded)
+		if err != nil {
+			t.Fatalf("Decoding %s failed: %v", encoded, err)
+		}
+
+		reencoded, _ := ss.EncodeClientJSON(decoded, false)
+		if !bytes.Equal(encoded, reencoded) {
+			t.Fatalf("Round trip mismatched.\nExpected: %s\nGot     : %s", encoded, reencoded)
+		}
+	})
+}
+
+// fuzzMethods ... Methods of generated URIs.
+var fuzzMethods = []string{"aes-256-gcm", "chacha20-ietf-poly1305", "bf-cfb", "rc4-md5", "2022-blake3-aes-128-gcm"}
+
+// generateURI ... Build a valid URI from fuzzer input.
+func generateURI(method uint8, password, host string, port uint16, tag, plugin, key, value string) *ss.ShadowsocksURI {
+	if password == "" {
+		password = "x"
+	}
+
+	// Hostnames are IP addresses or made of letters, digits, '.' and '-'.
+	if ip := net.ParseIP(host); ip == nil || strings.Contains(host, "%") {
+		host = strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+				return r
+			}
+
+			return -1
+		}, strings.ToLower(host))
+	}
+
+	if host == "" {
+		host = "example.com"
+	}
+
+	if port == 0 {
+		port = 8388
+	}
+
+	uri := &ss.ShadowsocksURI{
+		Remote: ss.NewServer(host, int(port)),
+		Auth:   ss.NewAuthInfo(fuzzMethods[int(method)%len(fuzzMethods)], password),
+		Tag:    tag,
+	}
+
+	// Decoders keep plugins as written, known or not, see ss.PluginInfo.Normalize.
+	if plugin != "" {
+		options := map[string]string{}
+		if key != "" {
+			options[key] = value
+		}
+
+		uri.Plugin = ss.NewPlugin(plugin, options)
+	}
+
+	return uri
+}
+
+func FuzzURIRoundTrip(f *testing.F) {
+	f.Add(uint8(0), "correct horse", "example.com", uint16(8388), "HK 01", "", "", "")
+	f.Add(uint8(1), "p@ss:w#rd%41/?", "::1", uint16(443), "\U0001F1EF\U0001F1F5 Tokyo #1", "my-plugin", "k;e=y", "v\\a;l=ue")
+	f.Add(uint8(2), "=;&+", "192.168.100.1", uint16(1), "[::1]:443 %5B", "p", "tls", "")
+	f.Add(uint8(3), "correct horse", "example.com", uint16(8388), "50%25", "simple-obfs", "obfs", "http")
+	f.Add(uint8(4), "correct horse", "example.com", uint16(8388), "", "v2ray-plugin", "mode", "grpc")
+
+	f.Fuzz(func(t *testing.T, method uint8, password, host string, port uint16, tag, plugin, key, value string) {
+		uri := generateURI(method, password, host, port, tag, plugin, key, value)
+
+		checkURIRoundTrip(t, uri, (*ss.ShadowsocksURI).EncodeSIP002URI, ss.DecodeSIP002URI)
+		checkURIRoundTrip(t, uri, (*ss.ShadowsocksURI).EncodeSIP002URI, ss.DecodeURI)
+
+		legacy := uri.Copy()
+		legacy.Plugin = nil
+
+		checkURIRoundTrip(t, legacy, (*ss.ShadowsocksURI).EncodeBase64URI, ss.DecodeBase64URI)
+		checkURIRoundTrip(t, legacy, (*ss.ShadowsocksURI).EncodeBase64URI, ss.DecodeURI)
+		checkURIRoundTrip(t, withoutTag(legacy), (*ss.ShadowsocksURI).EncodePlainURI, ss.DecodePlainURI)
+		checkURIRoundTrip(t, withoutTag(legacy), (*ss.ShadowsocksURI).EncodePlainURI, ss.DecodeURI)
+	})
+}
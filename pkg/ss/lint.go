@@ -0,0 +1,339 @@
+package ss
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Severity ... Severity of a lint finding.
+type Severity int
+
+const (
+	// SeverityInfo ... Worth knowing, nothing is wrong.
+	SeverityInfo Severity = iota
+	// SeverityWarning ... Works, but weakens security or interoperability.
+	SeverityWarning
+	// SeverityError ... Broken or insecure, should be fixed.
+	SeverityError
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+// String ... Returns name of severity.
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return "unknown"
+	}
+
+	return severityNames[s]
+}
+
+// ParseSeverity ... Parse severity by its name.
+func ParseSeverity(s string) (Severity, error) {
+	for i, name := range severityNames {
+		if name == s {
+			return Severity(i), nil
+		}
+	}
+
+	return SeverityInfo, errors.New("invalid severity: " + s)
+}
+
+// MarshalText ... Encode severity by its name, e.g. in JSON.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Finding ... A problem found by Lint.
+type Finding struct {
+	Index    int      `json:"index"`    // Index of server in the linted list
+	Server   string   `json:"server"`   // Tag, or address of untagged server
+	Check    string   `json:"check"`    // Name of check, e.g. "cipher"
+	Severity Severity `json:"severity"` // Severity of problem
+	Message  string   `json:"message"`  // Human readable description
+}
+
+// String ... Format finding as one line.
+func (f *Finding) String() string {
+	return fmt.Sprintf("#%d %s: %s: [%s] %s", f.Index+1, f.Server, f.Severity, f.Check, f.Message)
+}
+
+// LintOptions ... Options of Lint.
+type LintOptions struct {
+	// LookupHost resolves hostnames to check them for private addresses.
+	// Hostnames are not resolved if nil, IP addresses are always checked.
+	LookupHost func(host string) ([]string, error)
+
+	// MinPasswordLength is the length below which passwords are an error,
+	// 8 if zero. Passwords shorter than twice of it are a warning.
+	MinPasswordLength int
+}
+
+// brokenCiphers ... Stream ciphers with practical attacks against them.
+var brokenCiphers = map[string]bool{
+	"table": true, "rc4": true, "rc4-md5": true, "rc4-md5-6": true,
+	"bf-cfb": true, "des-cfb": true, "idea-cfb": true, "rc2-cfb": true,
+	"cast5-cfb": true, "seed-cfb": true, "salsa20": true,
+}
+
+// streamCiphers ... Stream ciphers deprecated by the shadowsocks project in favor of AEAD.
+var streamCiphers = map[string]bool{
+	"aes-128-cfb": true, "aes-192-cfb": true, "aes-256-cfb": true,
+	"aes-128-ctr": true, "aes-192-ctr": true, "aes-256-ctr": true,
+	"aes-128-ofb": true, "aes-192-ofb": true, "aes-256-ofb": true,
+	"camellia-128-cfb": true, "camellia-192-cfb": true, "camellia-256-cfb": true,
+	"chacha20": true, "chacha20-ietf": true,
+}
+
+// aeadCiphers ... Recommended AEAD ciphers, see SIP004 and SIP022.
+var aeadCiphers = map[string]bool{
+	"aes-128-gcm": true, "aes-192-gcm": true, "aes-256-gcm": true,
+	"chacha20-ietf-poly1305": true, "xchacha20-ietf-poly1305": true,
+	"2022-blake3-aes-128-gcm": true, "2022-blake3-aes-256-gcm": true,
+	"2022-blake3-chacha20-poly1305": true,
+}
+
+// commonPasswords ... Passwords found at the top of every wordlist.
+var commonPasswords = map[string]bool{
+	"password": true, "passwd": true, "123456": true, "12345678": true,
+	"123456789": true, "qwerty": true, "test": true, "admin": true,
+	"shadowsocks": true, "barfoo!": true, "changeme": true, "abc123": true,
+}
+
+// insecurePluginOptions ... Plugin options turning off certificate verification.
+var insecurePluginOptions = map[string]bool{
+	"insecure": true, "allowinsecure": true, "skip-cert-verify": true, "skipverify": true,
+}
+
+// Lint ... Check servers for security and configuration problems.
+// Findings are ordered by server, then by check.
+func Lint(uris []*ShadowsocksURI, opts *LintOptions) []*Finding {
+	if opts == nil {
+		opts = &LintOptions{}
+	}
+
+	findings := []*Finding{}
+	seen := make(map[string]int)
+
+	for i, uri := range uris {
+		l := &linter{index: i, uri: uri, opts: opts}
+
+		l.checkCipher()
+		l.checkPassword()
+		l.checkPort()
+		l.checkHost()
+		l.checkPlugin()
+
+		if first, ok := seen[uri.Fingerprint()]; ok {
+			l.report("duplicate", SeverityWarning, "same server as #%d", first+1)
+		} else {
+			seen[uri.Fingerprint()] = i
+		}
+
+		if uri.Tag == "" {
+			l.report("tag", SeverityInfo, "missing tag")
+		}
+
+		findings = append(findings, l.findings...)
+	}
+
+	return findings
+}
+
+// MaxSeverity ... Returns highest severity of findings, -1 if there is none.
+func MaxSeverity(findings []*Finding) Severity {
+	max := Severity(-1)
+
+	for _, f := range findings {
+		if f.Severity > max {
+			max = f.Severity
+		}
+	}
+
+	return max
+}
+
+// linter ... Collects findings of one server.
+type linter struct {
+	index    int
+	uri      *ShadowsocksURI
+	opts     *LintOptions
+	findings []*Finding
+}
+
+// report ... Record a finding.
+func (l *linter) report(check string, severity Severity, format string, args ...interface{}) {
+	server := l.uri.Tag
+	if server == "" {
+		server = l.uri.Remote.String()
+	}
+
+	l.findings = append(l.findings, &Finding{
+		Index:    l.index,
+		Server:   server,
+		Check:    check,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) checkCipher() {
+	method := strings.ToLower(l.uri.Auth.Method())
+
+	switch {
+	case aeadCiphers[method]:
+	case brokenCiphers[method]:
+		l.report("cipher", SeverityError, "%s is a broken stream cipher, use an AEAD cipher such as chacha20-ietf-poly1305", method)
+	case streamCiphers[method]:
+		l.report("cipher", SeverityWarning, "%s is a deprecated stream cipher, use an AEAD cipher such as aes-256-gcm", method)
+	case method == "none" || method == "plain":
+		l.report("cipher", SeverityError, "traffic is not encrypted")
+	default:
+		l.report("cipher", SeverityError, "unknown method %q", method)
+	}
+}
+
+func (l *linter) checkPassword() {
+	// SIP022 methods use base64 keys of a fixed length instead of passwords.
+	if strings.HasPrefix(strings.ToLower(l.uri.Auth.Method()), "2022-") {
+		return
+	}
+
+	minLength := l.opts.MinPasswordLength
+	if minLength == 0 {
+		minLength = 8
+	}
+
+	password := l.uri.Auth.Password()
+
+	switch {
+	case len(password) == 0:
+		l.report("password", SeverityError, "password is empty")
+	case commonPasswords[strings.ToLower(password)]:
+		l.report("password", SeverityError, "password is a common password")
+	case len(password) < minLength:
+		l.report("password", SeverityError, "password is shorter than %d characters", minLength)
+	case strings.Count(password, password[:1]) == len(password):
+		l.report("password", SeverityError, "password repeats a single character")
+	case len(password) < 2*minLength:
+		l.report("password", SeverityWarning, "password is shorter than %d characters", 2*minLength)
+	}
+}
+
+func (l *linter) checkPort() {
+	port := l.uri.Remote.Port()
+
+	switch {
+	case port <= 0 || port > 65535:
+		l.report("port", SeverityError, "port %d is out of range", port)
+	case port < 1024:
+		l.report("port", SeverityWarning, "port %d is a privileged port", port)
+	}
+}
+
+func (l *linter) checkHost() {
+	hostname := normalizeHostname(l.uri.Remote.Hostname())
+
+	if hostname == "" {
+		l.report("host", SeverityError, "missing hostname")
+		return
+	}
+
+	if ip := net.ParseIP(hostname); ip != nil {
+		if isPrivateIP(ip) {
+			l.report("host", SeverityWarning, "%s is a private or local address", hostname)
+		}
+
+		return
+	}
+
+	if l.opts.LookupHost == nil {
+		return
+	}
+
+	addrs, err := l.opts.LookupHost(hostname)
+	if err != nil {
+		l.report("host", SeverityWarning, "cannot resolve %s: %v", hostname, err)
+		return
+	}
+
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && isPrivateIP(ip) {
+			l.report("host", SeverityWarning, "%s resolves to private or local address %s", hostname, addr)
+			return
+		}
+	}
+}
+
+func (l *linter) checkPlugin() {
+	if l.uri.Plugin == nil {
+		return
+	}
+
+	// Aliases like simple-obfs or kcptun-client are checked as their programs.
+	plugin := l.uri.Plugin.Normalize()
+
+	switch plugin.Name() {
+	case "obfs-local":
+		l.report("plugin", SeverityWarning, "simple-obfs is deprecated, consider v2ray-plugin with TLS")
+	case "v2ray-plugin":
+		if _, ok := plugin.Options()["tls"]; !ok {
+			l.report("plugin", SeverityInfo, "v2ray-plugin without tls is easy to fingerprint")
+		}
+	case "kcptun":
+		if plugin.Options()["crypt"] == "none" {
+			l.report("plugin", SeverityWarning, "kcptun with crypt=none does not hide the protocol")
+		}
+	}
+
+	for _, k := range l.uri.Plugin.optionKeys() {
+		if insecurePluginOptions[strings.ToLower(k)] {
+			l.report("plugin", SeverityError, "plugin option %s disables certificate verification", k)
+		}
+	}
+
+	schema := LookupPluginSchema(l.uri.Plugin.Name())
+	if schema == nil {
+		return
+	}
+
+	for _, problem := range schema.Check(l.uri.Plugin.Options()) {
+		switch {
+		case insecurePluginOptions[strings.ToLower(problem.Key)]:
+			// Reported above.
+		case problem.Unknown:
+			l.report("plugin", SeverityWarning, "%v", problem)
+		default:
+			l.report("plugin", SeverityError, "%v", problem)
+		}
+	}
+}
+
+// isPrivateIP ... Reports whether ip is not publicly routable.
+func isPrivateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return true
+	}
+
+	for _, cidr := range privateNetworks {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// privateNetworks ... RFC 1918, RFC 6598 (CGNAT) and RFC 4193 (IPv6 ULA) ranges.
+var privateNetworks = func() []*net.IPNet {
+	networks := []*net.IPNet{}
+
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		networks = append(networks, n)
+	}
+
+	return networks
+}()
@@ -0,0 +1,119 @@
+package ss_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestLint(t *testing.T) {
+	uris := []*ss.ShadowsocksURI{
+		{
+			Remote: ss.NewServer("example.com", 8388),
+			Auth:   ss.NewAuthInfo("chacha20-ietf-poly1305", "correct horse battery"),
+			Tag:    "good",
+		},
+		{
+			Remote: ss.NewServer("192.168.100.1", 443),
+			Auth:   ss.NewAuthInfo("rc4-md5", "test"),
+			Plugin: ss.NewPlugin("v2ray-plugin", map[string]string{"tls": "", "allowInsecure": ""}),
+		},
+		{
+			Remote: ss.NewServer("EXAMPLE.com", 8388),
+			Auth:   ss.NewAuthInfo("aes-256-cfb", "correct horse battery"),
+			Tag:    "copy",
+		},
+		{
+			Remote: ss.NewServer("internal.example.com", 8388),
+			Auth:   ss.NewAuthInfo("aes-256-gcm", "short pass"),
+			Tag:    "internal",
+		},
+	}
+
+	opts := &ss.LintOptions{
+		LookupHost: func(host string) ([]string, error) {
+			switch host {
+			case "internal.example.com":
+				return []string{"10.0.0.1"}, nil
+			case "example.com":
+				return []string{"93.184.216.34"}, nil
+			}
+
+			return nil, errors.New("no such host")
+		},
+	}
+
+	expected := []struct {
+		index    int
+		check    string
+		severity ss.Severity
+	}{
+		{1, "cipher", ss.SeverityError},
+		{1, "password", ss.SeverityError},
+		{1, "port", ss.SeverityWarning},
+		{1, "host", ss.SeverityWarning},
+		{1, "plugin", ss.SeverityError},
+		{1, "tag", ss.SeverityInfo},
+		{2, "cipher", ss.SeverityWarning},
+		{3, "password", ss.SeverityWarning},
+		{3, "host", ss.SeverityWarning},
+	}
+
+	findings := ss.Lint(uris, opts)
+
+	// Duplicates differ in method here, so only an exact copy is reported.
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, Got: %v", len(expected), findings)
+	}
+
+	for i, f := range findings {
+		e := expected[i]
+		if f.Index != e.index || f.Check != e.check || f.Severity != e.severity {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v", i, e, f)
+		}
+	}
+
+	if ss.MaxSeverity(findings) != ss.SeverityError {
+		t.Errorf("Expected max severity error, Got: %v", ss.MaxSeverity(findings))
+	}
+
+	findings = ss.Lint([]*ss.ShadowsocksURI{uris[0], uris[0]}, nil)
+	if len(findings) != 1 || findings[0].Check != "duplicate" || findings[0].Index != 1 {
+		t.Errorf("Expected duplicate, Got: %v", findings)
+	}
+
+	if ss.MaxSeverity(ss.Lint(uris[:1], nil)) != -1 {
+		t.Errorf("Expected no findings for a good server")
+	}
+
+	// Negative lengths only turn off the length checks.
+	tests := []struct {
+		password string
+		severity ss.Severity
+	}{
+		{"", ss.SeverityError},
+		{"aaaa", ss.SeverityError},
+		{"password", ss.SeverityError},
+		{"x7", -1},
+	}
+
+	for i, ut := range tests {
+		uri := &ss.ShadowsocksURI{
+			Remote: ss.NewServer("example.com", 8388),
+			Auth:   ss.NewAuthInfo("aes-256-gcm", ut.password),
+			Tag:    "test",
+		}
+
+		findings := ss.Lint([]*ss.ShadowsocksURI{uri}, &ss.LintOptions{MinPasswordLength: -1})
+		if ss.MaxSeverity(findings) != ut.severity {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v", i, ut.severity, findings)
+		}
+	}
+
+	for _, name := range []string{"info", "warning", "error"} {
+		if s, err := ss.ParseSeverity(name); err != nil || s.String() != name {
+			t.Errorf("Expected severity %v, Got: %v, %v", name, s, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package ss
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// TagPolicy ... Policy deciding the tag of merged duplicate servers.
+type TagPolicy int
+
+const (
+	// TagFirst ... Keep the tag of the first occurrence.
+	TagFirst TagPolicy = iota
+	// TagLast ... Keep the tag of the last occurrence.
+	TagLast
+	// TagJoin ... Join all distinct tags with TagSeparator.
+	TagJoin
+	// TagLongest ... Keep the longest tag in characters, earliest on ties.
+	TagLongest
+)
+
+// TagSeparator ... Separator used by TagJoin.
+const TagSeparator = "/"
+
+var tagPolicyNames = map[TagPolicy]string{
+	TagFirst:   "first",
+	TagLast:    "last",
+	TagJoin:    "join",
+	TagLongest: "longest",
+}
+
+// String ... Returns name of tag policy.
+func (p TagPolicy) String() string {
+	return tagPolicyNames[p]
+}
+
+// ParseTagPolicy ... Parse tag policy by its name.
+func ParseTagPolicy(s string) (TagPolicy, error) {
+	for p, name := range tagPolicyNames {
+		if name == s {
+			return p, nil
+		}
+	}
+
+	return TagFirst, errors.New("invalid tag policy: " + s)
+}
+
+// MergeGroup ... A set of input servers sharing the same identity.
+type MergeGroup struct {
+	Merged  *ShadowsocksURI // Resulting server
+	Indices []int           // Indices of inputs collapsed into Merged
+}
+
+// MergeResult ... Result of merging a server list.
+type MergeResult struct {
+	URIs   []*ShadowsocksURI // Deduplicated servers, in order of first occurrence
+	Groups []*MergeGroup     // One group per resulting server
+}
+
+// Collapsed ... Returns groups that merged more than one input.
+func (r *MergeResult) Collapsed() []*MergeGroup {
+	groups := []*MergeGroup{}
+
+	for _, g := range r.Groups {
+		if len(g.Indices) > 1 {
+			groups = append(groups, g)
+		}
+	}
+
+	return groups
+}
+
+// Merge ... Deduplicate servers by Fingerprint() and merge their tags.
+// The first occurrence of a server wins, except that a missing plugin is
+// taken from a later duplicate. Inputs are not modified.
+func Merge(uris []*ShadowsocksURI, policy TagPolicy) *MergeResult {
+	result := &MergeResult{
+		URIs:   []*ShadowsocksURI{},
+		Groups: []*MergeGroup{},
+	}
+
+	seen := make(map[string]*MergeGroup)
+	tags := make(map[*MergeGroup][]string)
+
+	for i, uri := range uris {
+		fp := uri.Fingerprint()
+
+		g, ok := seen[fp]
+		if !ok {
+			g = &MergeGroup{Merged: uri.Copy()}
+			seen[fp] = g
+			result.Groups = append(result.Groups, g)
+			result.URIs = append(result.URIs, g.Merged)
+		} else if g.Merged.Plugin == nil && uri.Plugin != nil {
+			g.Merged.Plugin = uri.Copy().Plugin
+		}
+
+		g.Indices = append(g.Indices, i)
+		tags[g] = append(tags[g], uri.Tag)
+	}
+
+	for _, g := range result.Groups {
+		g.Merged.Tag = mergeTags(tags[g], policy)
+	}
+
+	return result
+}
+
+// Dedupe ... Deduplicate servers, see Merge().
+func Dedupe(uris []*ShadowsocksURI, policy TagPolicy) []*ShadowsocksURI {
+	return Merge(uris, policy).URIs
+}
+
+// mergeTags ... Merge tags according to policy, ignoring empty tags.
+func mergeTags(tags []string, policy TagPolicy) string {
+	nonEmpty := []string{}
+
+	for _, t := range tags {
+		if t != "" {
+			nonEmpty = append(nonEmpty, t)
+		}
+	}
+
+	if len(nonEmpty) == 0 {
+		return ""
+	}
+
+	switch policy {
+	case TagLast:
+		return nonEmpty[len(nonEmpty)-1]
+	case TagJoin:
+		distinct := []string{}
+		seen := make(map[string]bool)
+
+		for _, t := range nonEmpty {
+			if !seen[t] {
+				seen[t] = true
+				distinct = append(distinct, t)
+			}
+		}
+
+		return strings.Join(distinct, TagSeparator)
+	case TagLongest:
+		longest := nonEmpty[0]
+
+		for _, t := range nonEmpty[1:] {
+			if utf8.RuneCountInString(t) > utf8.RuneCountInString(longest) {
+				longest = t
+			}
+		}
+
+		return longest
+	}
+
+	return nonEmpty[0]
+}
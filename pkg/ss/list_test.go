@@ -0,0 +1,133 @@
+package ss_test
+
+import (
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestDecodeURIList(t *testing.T) {
+	data := `
+ss://YmYtY2ZiOnRlc3Q=@192.168.100.1:8888#sip002
+ss://YmYtY2ZiOnRlc3RAMTkyLjE2OC4xMDAuMTo4ODg4#legacy
+
+ss://bf-cfb:test@192.168.100.1:8888#plain
+`
+
+	uris, err := ss.DecodeURIList(data)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(uris) != 3 {
+		t.Fatalf("Expected 3 URIs, Got: %d", len(uris))
+	}
+
+	for i, tag := range []string{"sip002", "legacy", "plain"} {
+		expected := &ss.ShadowsocksURI{
+			Remote: ss.NewServer("192.168.100.1", 8888),
+			Auth:   ss.NewAuthInfo("bf-cfb", "test"),
+			Tag:    tag,
+		}
+
+		if !uris[i].Equal(expected) {
+			t.Errorf("#%d test failed.\nExpected: %v\nGot     : %v", i, *expected, *uris[i])
+		}
+	}
+
+	if _, err := ss.DecodeURIList("ss://YmYtY2ZiOnRlc3Q=@192.168.100.1:8888\nhttp://x"); err == nil {
+		t.Errorf("Expected error for invalid line")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	obfs := ss.NewPlugin("obfs-local", map[string]string{"obfs": "http"})
+
+	uris := []*ss.ShadowsocksURI{
+		{Remote: ss.NewServer("Example.com", 8888), Auth: ss.NewAuthInfo("bf-cfb", "test"), Tag: "hk"},
+		{Remote: ss.NewServer("192.168.100.1", 8888), Auth: ss.NewAuthInfo("bf-cfb", "test"), Tag: "a"},
+		{Remote: ss.NewServer("example.com", 8888), Auth: ss.NewAuthInfo("BF-CFB", "test"), Tag: "hong-kong", Plugin: obfs},
+		{Remote: ss.NewServer("example.com", 8888), Auth: ss.NewAuthInfo("bf-cfb", "test"), Tag: "hk"},
+		{Remote: ss.NewServer("example.com", 8888), Auth: ss.NewAuthInfo("bf-cfb", "other"), Tag: "hk"},
+	}
+
+	tests := []struct {
+		policy   ss.TagPolicy
+		expected string
+	}{
+		{ss.TagFirst, "hk"},
+		{ss.TagLast, "hk"},
+		{ss.TagJoin, "hk/hong-kong"},
+		{ss.TagLongest, "hong-kong"},
+	}
+
+	for i, ut := range tests {
+		result := ss.Merge(uris, ut.policy)
+
+		if len(result.URIs) != 3 {
+			t.Errorf("#%d test failed. Expected 3 servers, Got: %d", i, len(result.URIs))
+			continue
+		}
+
+		if result.URIs[0].Tag != ut.expected {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v", i, ut.expected, result.URIs[0].Tag)
+		}
+
+		if !result.URIs[0].Plugin.Equal(obfs) {
+			t.Errorf("#%d test failed. Plugin should be merged from duplicates", i)
+		}
+
+		collapsed := result.Collapsed()
+		if len(collapsed) != 1 || len(collapsed[0].Indices) != 3 ||
+			collapsed[0].Indices[0] != 0 || collapsed[0].Indices[1] != 2 || collapsed[0].Indices[2] != 3 {
+			t.Errorf("#%d test failed. Unexpected collapsed groups", i)
+		}
+	}
+
+	if uris[0].Tag != "hk" || uris[0].Plugin != nil {
+		t.Errorf("Merge() should not modify inputs")
+	}
+}
+
+func TestMergeLongestTag(t *testing.T) {
+	tests := []struct {
+		tags     []string
+		expected string
+	}{
+		{[]string{"🇭🇰 香港", "Hong Kong"}, "Hong Kong"},
+		{[]string{"Hong Kong", "🇭🇰 香港"}, "Hong Kong"},
+		{[]string{"東京", "JP"}, "東京"},
+		{[]string{"JP", "東京"}, "JP"},
+		{[]string{"", "東京 01"}, "東京 01"},
+	}
+
+	for i, ut := range tests {
+		uris := []*ss.ShadowsocksURI{}
+
+		for _, tag := range ut.tags {
+			uris = append(uris, &ss.ShadowsocksURI{
+				Remote: ss.NewServer("example.com", 8888),
+				Auth:   ss.NewAuthInfo("bf-cfb", "test"),
+				Tag:    tag,
+			})
+		}
+
+		result := ss.Merge(uris, ss.TagLongest)
+		if len(result.URIs) != 1 || result.URIs[0].Tag != ut.expected {
+			t.Errorf("#%d test failed. Expected: %q, Got: %v", i, ut.expected, result.URIs)
+		}
+	}
+}
+
+func TestParseTagPolicy(t *testing.T) {
+	for _, p := range []ss.TagPolicy{ss.TagFirst, ss.TagLast, ss.TagJoin, ss.TagLongest} {
+		parsed, err := ss.ParseTagPolicy(p.String())
+		if err != nil || parsed != p {
+			t.Errorf("ParseTagPolicy(%q) failed", p.String())
+		}
+	}
+
+	if _, err := ss.ParseTagPolicy("random"); err == nil {
+		t.Errorf("Expected error for unknown policy")
+	}
+}
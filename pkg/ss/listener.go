@@ -0,0 +1,84 @@
+package ss
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+// errClosed ... Returned by Serve after Close.
+var errClosed = errors.New("ss: server closed")
+
+// listenerGroup ... Listeners and packet connections served by a client or
+// server, closed together.
+type listenerGroup struct {
+	mu      sync.Mutex
+	closers map[io.Closer]bool
+	closed  bool
+}
+
+// add ... Track c, returns false and closes c if the group is closed.
+func (g *listenerGroup) add(c io.Closer) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		c.Close()
+		return false
+	}
+
+	if g.closers == nil {
+		g.closers = make(map[io.Closer]bool)
+	}
+
+	g.closers[c] = true
+
+	return true
+}
+
+// remove ... Stop tracking c, returns errClosed if the group was closed, err otherwise.
+func (g *listenerGroup) remove(c io.Closer, err error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.closers, c)
+
+	if g.closed {
+		return errClosed
+	}
+
+	return err
+}
+
+// serve ... Accept connections of ln and handle each in its own goroutine
+// until ln fails or the group is closed.
+func (g *listenerGroup) serve(ln net.Listener, handle func(conn net.Conn)) error {
+	if !g.add(ln) {
+		return errClosed
+	}
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return g.remove(ln, err)
+		}
+
+		go handle(conn)
+	}
+}
+
+// close ... Close everything tracked, later calls of add fail.
+func (g *listenerGroup) close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.closed = true
+
+	for c := range g.closers {
+		c.Close()
+		delete(g.closers, c)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package ss
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ClientOptions ... Client settings that a shadowsocks URI cannot carry, applied
+// when converting URIs to client configurations.
+type ClientOptions struct {
+	Local      *Server // Local listen address
+	Timeout    int     // Connection timeout in seconds
+	FastOpen   bool    // TCP fast open
+	Workers    int     // Number of workers, available on Unix/Linux
+	Mode       string  // tcp_only, udp_only or tcp_and_udp, omitted if empty
+	Nameserver string  // DNS server, omitted if empty
+}
+
+// DefaultClientOptions ... Returns the built-in defaults, 127.0.0.1:1080,
+// timeout 300, no fast open and 1 worker.
+func DefaultClientOptions() *ClientOptions {
+	return &ClientOptions{
+		Local:    NewServer("127.0.0.1", 1080),
+		Timeout:  300,
+		FastOpen: false,
+		Workers:  1,
+	}
+}
+
+// LoadClientOptions ... Read options from a defaults file in client JSON
+// format, e.g. {"local_port": 1081, "timeout": 60}. Fields missing from the
+// file are taken from base.
+func LoadClientOptions(data []byte, base *ClientOptions) (*ClientOptions, error) {
+	if base == nil {
+		base = DefaultClientOptions()
+	}
+
+	defaults := struct {
+		LocalAddress string `json:"local_address"`
+		LocalPort    int    `json:"local_port"`
+		Timeout      int    `json:"timeout"`
+		FastOpen     bool   `json:"fast_open"`
+		Workers      int    `json:"workers"`
+		Mode         string `json:"mode"`
+		Nameserver   string `json:"nameserver"`
+	}{
+		LocalAddress: base.Local.Hostname(),
+		LocalPort:    base.Local.Port(),
+		Timeout:      base.Timeout,
+		FastOpen:     base.FastOpen,
+		Workers:      base.Workers,
+		Mode:         base.Mode,
+		Nameserver:   base.Nameserver,
+	}
+
+	if err := json.Unmarshal(data, &defaults); err != nil {
+		return nil, err
+	}
+
+	opts := &ClientOptions{
+		Local:      NewServer(defaults.LocalAddress, defaults.LocalPort),
+		Timeout:    defaults.Timeout,
+		FastOpen:   defaults.FastOpen,
+		Workers:    defaults.Workers,
+		Mode:       defaults.Mode,
+		Nameserver: defaults.Nameserver,
+	}
+
+	return opts, opts.Validate()
+}
+
+// Validate ... Check options for out of range values.
+func (opts *ClientOptions) Validate() error {
+	if opts.Local == nil || opts.Local.Hostname() == "" {
+		return errors.New("invalid local address")
+	}
+
+	if opts.Local.Port() < 0 || opts.Local.Port() > 65535 {
+		return errors.New("invalid local port")
+	}
+
+	if opts.Timeout < 0 || opts.Workers < 0 {
+		return errors.New("invalid timeout or workers")
+	}
+
+	return checkMode(opts.Mode)
+}
+
+// Relay modes of ClientOptions and ShadowsocksClientConfig, empty means ModeTCPOnly.
+const (
+	ModeTCPOnly   = "tcp_only"
+	ModeUDPOnly   = "udp_only"
+	ModeTCPAndUDP = "tcp_and_udp"
+)
+
+// checkMode ... Returns error if mode is not a relay mode.
+func checkMode(mode string) error {
+	switch mode {
+	case "", ModeTCPOnly, ModeUDPOnly, ModeTCPAndUDP:
+		return nil
+	}
+
+	return errors.New("invalid mode: " + mode)
+}
+
+// relaysTCP ... Reports whether mode relays TCP.
+func relaysTCP(mode string) bool {
+	return mode != ModeUDPOnly
+}
+
+// relaysUDP ... Reports whether mode relays UDP.
+func relaysUDP(mode string) bool {
+	return mode == ModeUDPOnly || mode == ModeTCPAndUDP
+}
+
+// ToShadowsocksClientConfigWithOptions ... Convert shadowsocks URI to client configuration using opts.
+func ToShadowsocksClientConfigWithOptions(uri *ShadowsocksURI, opts *ClientOptions) *ShadowsocksClientConfig {
+	return &ShadowsocksClientConfig{
+		Remote:     uri.Remote,
+		Auth:       uri.Auth,
+		Local:      NewServer(opts.Local.Hostname(), opts.Local.Port()),
+		Timeout:    opts.Timeout,
+		FastOpen:   opts.FastOpen,
+		Workers:    opts.Workers,
+		Plugin:     uri.Plugin,
+		Tag:        uri.Tag,
+		Mode:       opts.Mode,
+		Nameserver: opts.Nameserver,
+	}
+}
@@ -0,0 +1,53 @@
+package ss_test
+
+import (
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestClientOptions(t *testing.T) {
+	opts, err := ss.LoadClientOptions([]byte(`{"local_port": 1081, "timeout": 60, "mode": "tcp_and_udp"}`), nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	uri := &ss.ShadowsocksURI{
+		Remote: ss.NewServer("192.168.100.1", 8888),
+		Auth:   ss.NewAuthInfo("bf-cfb", "test"),
+		Tag:    "example-server",
+	}
+
+	cc := ss.ToShadowsocksClientConfigWithOptions(uri, opts)
+
+	expected := &ss.ShadowsocksClientConfig{
+		Remote:   ss.NewServer("192.168.100.1", 8888),
+		Local:    ss.NewServer("127.0.0.1", 1081),
+		Auth:     ss.NewAuthInfo("bf-cfb", "test"),
+		Timeout:  60,
+		FastOpen: false,
+		Workers:  1,
+	}
+
+	if !checkClientConfig(cc, expected) || cc.Mode != "tcp_and_udp" || cc.Tag != "example-server" {
+		t.Errorf("Expected:\n%v,\nGot:\n%v", *expected, *cc)
+	}
+
+	// JSON values take precedence over options.
+	cc, err = ss.DecodeJSONWithOptions([]byte(`{"server": "192.168.100.1", "server_port": 8888, "method": "bf-cfb", "password": "test", "timeout": 5, "mode": "tcp_only"}`), opts)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	expected.Timeout = 5
+
+	if !checkClientConfig(cc, expected) || cc.Mode != "tcp_only" {
+		t.Errorf("Expected:\n%v,\nGot:\n%v", *expected, *cc)
+	}
+
+	for _, data := range []string{`{"mode": "udp"}`, `{"local_port": 70000}`, `{"local_address": ""}`, `[]`} {
+		if _, err := ss.LoadClientOptions([]byte(data), nil); err == nil {
+			t.Errorf("Expected error for %v", data)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ss
+
+// Stage ... One step of a Pipeline over a server list.
+type Stage func(uris []*ShadowsocksURI) ([]*ShadowsocksURI, error)
+
+// Pipeline ... Ordered stages transforming a server list.
+type Pipeline []Stage
+
+// Run ... Run stages in order on a deep copy of uris.
+func (p Pipeline) Run(uris []*ShadowsocksURI) ([]*ShadowsocksURI, error) {
+	out := make([]*ShadowsocksURI, len(uris))
+
+	for i, uri := range uris {
+		out[i] = uri.Copy()
+	}
+
+	for _, stage := range p {
+		var err error
+
+		out, err = stage(out)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// FilterStage ... Stage keeping servers that match all filters.
+func FilterStage(filters ...Filter) Stage {
+	return func(uris []*ShadowsocksURI) ([]*ShadowsocksURI, error) {
+		return Select(uris, filters...), nil
+	}
+}
+
+// SortStage ... Stage sorting servers, see SortBy().
+func SortStage(fields ...string) Stage {
+	return func(uris []*ShadowsocksURI) ([]*ShadowsocksURI, error) {
+		return uris, SortBy(uris, fields...)
+	}
+}
+
+// RenameStage ... Stage rewriting tags, see Rename().
+func RenameStage(text string) Stage {
+	return func(uris []*ShadowsocksURI) ([]*ShadowsocksURI, error) {
+		return uris, Rename(uris, text)
+	}
+}
+
+// DedupeStage ... Stage deduplicating servers, see Merge().
+func DedupeStage(policy TagPolicy) Stage {
+	return func(uris []*ShadowsocksURI) ([]*ShadowsocksURI, error) {
+		return Dedupe(uris, policy), nil
+	}
+}
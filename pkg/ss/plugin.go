@@ -0,0 +1,216 @@
+package ss
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// pluginStartTimeout ... Time a plugin has to accept connections after it is started.
+const pluginStartTimeout = 10 * time.Second
+
+// pluginRestartDelay ... Wait before restarting a plugin that exited.
+const pluginRestartDelay = time.Second
+
+// PluginProcess ... SIP003 plugin forwarding connections from a local port to
+// a shadowsocks server. The process is restarted whenever it exits, until
+// Close is called. See https://shadowsocks.org/en/wiki/Plugin.html
+type PluginProcess struct {
+	plugin *PluginInfo
+	remote *Server
+	local  *Server
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	closed   bool
+	stopping chan struct{}
+	done     chan struct{}
+}
+
+// StartPlugin ... Start plugin for remote on a free port of 127.0.0.1 and wait
+// until it accepts connections. The plugin name is the program to run, looked
+// up in PATH unless it is a path. Its output goes to the standard error.
+func StartPlugin(plugin *PluginInfo, remote *Server) (*PluginProcess, error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PluginProcess{
+		plugin:   plugin,
+		remote:   remote,
+		local:    NewServer("127.0.0.1", port),
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	cmd, err := p.start()
+	if err != nil {
+		return nil, err
+	}
+
+	exited := wait(cmd)
+
+	if err := waitListening(p.Addr(), exited); err != nil {
+		cmd.Process.Kill()
+		return nil, errors.New("plugin " + plugin.Name() + ": " + err.Error())
+	}
+
+	go p.supervise(exited)
+
+	return p, nil
+}
+
+// Addr ... Returns "host:port" the plugin listens on, to be used in place of the server address.
+func (p *PluginProcess) Addr() string {
+	return dialAddress(p.local)
+}
+
+// Close ... Stop the plugin and wait for it to exit.
+func (p *PluginProcess) Close() error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.stopping)
+		p.cmd.Process.Kill()
+	}
+	p.mu.Unlock()
+
+	<-p.done
+
+	return nil
+}
+
+// start ... Run the plugin process with the SIP003 environment.
+func (p *PluginProcess) start() (*exec.Cmd, error) {
+	cmd := exec.Command(p.plugin.Name())
+	cmd.Env = append(os.Environ(),
+		"SS_REMOTE_HOST="+normalizeHostname(p.remote.Hostname()),
+		"SS_REMOTE_PORT="+strconv.Itoa(p.remote.Port()),
+		"SS_LOCAL_HOST="+p.local.Hostname(),
+		"SS_LOCAL_PORT="+strconv.Itoa(p.local.Port()),
+		"SS_PLUGIN_OPTIONS="+p.plugin.OptionsString(),
+	)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p.cmd = cmd
+
+	return cmd, nil
+}
+
+// supervise ... Restart the plugin each time it exits until Close is called.
+func (p *PluginProcess) supervise(exited <-chan error) {
+	defer close(p.done)
+
+	for {
+		select {
+		case <-exited:
+		case <-p.stopping:
+			<-exited
+			return
+		}
+
+		select {
+		case <-time.After(pluginRestartDelay):
+		case <-p.stopping:
+			return
+		}
+
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+
+		cmd, err := p.start()
+		p.mu.Unlock()
+
+		if err != nil {
+			failed := make(chan error, 1)
+			failed <- err
+			exited = failed
+
+			continue
+		}
+
+		exited = wait(cmd)
+	}
+}
+
+// wait ... Returns channel receiving the result of cmd.Wait.
+func wait(cmd *exec.Cmd) <-chan error {
+	exited := make(chan error, 1)
+
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	return exited
+}
+
+// waitListening ... Wait until addr accepts connections, or the process exits.
+func waitListening(addr string, exited <-chan error) error {
+	deadline := time.Now().Add(pluginStartTimeout)
+
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New("not listening on " + addr)
+		}
+
+		select {
+		case err := <-exited:
+			if err == nil {
+				return errors.New("exited")
+			}
+
+			return err
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// freePort ... Returns a TCP port of 127.0.0.1 that is free at the moment.
+func freePort() (int, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// pluginConn ... Connection through a plugin started for it alone, Close stops the plugin.
+type pluginConn struct {
+	net.Conn
+	plugin *PluginProcess
+}
+
+// Close ... Close the connection and stop the plugin.
+func (c *pluginConn) Close() error {
+	err := c.Conn.Close()
+	c.plugin.Close()
+
+	return err
+}
+
+// CloseWrite ... Shut down the writing side of the connection.
+func (c *pluginConn) CloseWrite() error {
+	closeWrite(c.Conn)
+	return nil
+}
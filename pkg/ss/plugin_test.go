@@ -0,0 +1,198 @@
+package ss_test
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+	"github.com/vgxbj/ssuri/pkg/ss/sstest"
+)
+
+// TestMain ... The test binary doubles as a fake SIP003 plugin when started by one.
+func TestMain(m *testing.M) {
+	if os.Getenv("SS_LOCAL_PORT") != "" {
+		fakePlugin()
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakePlugin ... Forward connections from SS_LOCAL to SS_REMOTE. Option env
+// names a file the environment is appended to on start, option exit makes it
+// exit after the first connection carrying data.
+func fakePlugin() {
+	options, _ := ss.ParsePluginOpts(os.Getenv("SS_PLUGIN_OPTIONS"))
+
+	if name := options["env"]; name != "" {
+		f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			os.Exit(2)
+		}
+
+		for _, k := range []string{"SS_REMOTE_HOST", "SS_REMOTE_PORT", "SS_LOCAL_HOST", "SS_LOCAL_PORT", "SS_PLUGIN_OPTIONS"} {
+			io.WriteString(f, k+"="+os.Getenv(k)+"\n")
+		}
+
+		f.Close()
+	}
+
+	ln, err := net.Listen("tcp", net.JoinHostPort(os.Getenv("SS_LOCAL_HOST"), os.Getenv("SS_LOCAL_PORT")))
+	if err != nil {
+		os.Exit(2)
+	}
+
+	remote := net.JoinHostPort(os.Getenv("SS_REMOTE_HOST"), os.Getenv("SS_REMOTE_PORT"))
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			os.Exit(2)
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+
+			server, err := net.Dial("tcp", remote)
+			if err != nil {
+				return
+			}
+			defer server.Close()
+
+			go io.Copy(server, conn)
+			n, _ := io.Copy(conn, server)
+
+			if n > 0 && options["exit"] != "" {
+				os.Exit(0)
+			}
+		}(conn)
+	}
+}
+
+// fakePluginInfo ... Returns the test binary as plugin, recording its environment to a file.
+func fakePluginInfo(t *testing.T, options map[string]string) (*ss.PluginInfo, string) {
+	t.Helper()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("%v", err)
+	}
+
+	env := filepath.Join(t.TempDir(), "env")
+	options["env"] = env
+
+	return ss.NewPlugin(exe, options), env
+}
+
+func TestDialPlugin(t *testing.T) {
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer web.Close()
+
+	server := sstest.NewServer("aes-128-gcm", "correct horse")
+	defer server.Close()
+
+	uri := server.URI.Copy()
+	plugin, env := fakePluginInfo(t, map[string]string{"obfs": "http"})
+	uri.Plugin = plugin
+
+	conn, err := ss.Dial(uri, strings.TrimPrefix(web.URL, "http://"), 5*time.Second)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	io.WriteString(conn, "GET / HTTP/1.0\r\n\r\n")
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	conn.Close()
+
+	if string(body) != "hello" {
+		t.Errorf("Expected body through plugin, Got: %q", body)
+	}
+
+	recorded, _ := ioutil.ReadFile(env)
+
+	for _, line := range []string{
+		"SS_REMOTE_HOST=127.0.0.1\n",
+		"SS_REMOTE_PORT=" + strings.Split(uri.Remote.String(), ":")[1] + "\n",
+		"SS_LOCAL_HOST=127.0.0.1\n",
+		"SS_PLUGIN_OPTIONS=env=" + env + ";obfs=http\n",
+	} {
+		if !strings.Contains(string(recorded), line) {
+			t.Errorf("Expected plugin environment %q, Got: %q", line, recorded)
+		}
+	}
+
+	uri.Plugin = ss.NewPlugin("ssuri-no-such-plugin", nil)
+
+	if _, err := ss.Dial(uri, "127.0.0.1:80", time.Second); err == nil {
+		t.Errorf("Expected error for missing plugin")
+	}
+}
+
+func TestClientPluginRestart(t *testing.T) {
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer web.Close()
+
+	server := sstest.NewServer("chacha20-ietf-poly1305", "correct horse")
+	defer server.Close()
+
+	uri := server.URI.Copy()
+	plugin, env := fakePluginInfo(t, map[string]string{"exit": "1"})
+	uri.Plugin = plugin
+
+	proxyURL, _ := url.Parse("socks5://" + startClient(t, uri))
+	client := &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL), DisableKeepAlives: true},
+		Timeout:   2 * time.Second,
+	}
+
+	get := func() error {
+		resp, err := client.Get(web.URL)
+		if err != nil {
+			return err
+		}
+
+		resp.Body.Close()
+
+		return nil
+	}
+
+	starts := func() int {
+		recorded, _ := ioutil.ReadFile(env)
+		return strings.Count(string(recorded), "SS_LOCAL_PORT=")
+	}
+
+	if err := get(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// The plugin exits after the first request and is restarted.
+	deadline := time.Now().Add(10 * time.Second)
+
+	for starts() < 2 || get() != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected plugin to be restarted, Got: %d starts", starts())
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,441 @@
+package ss
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// pluginOption ... Values a plugin option accepts.
+type pluginOption struct {
+	values []string // allowed values, any if empty
+	flag   bool     // takes no value
+	number bool     // takes a non-negative integer
+}
+
+var (
+	anyValue    = pluginOption{}
+	flagOption  = pluginOption{flag: true}
+	numberValue = pluginOption{number: true}
+)
+
+// oneOf ... Returns option accepting the given values only.
+func oneOf(values ...string) pluginOption {
+	return pluginOption{values: values}
+}
+
+// PluginSchema ... Options accepted by a SIP003 plugin.
+type PluginSchema struct {
+	Name       string            // program name, e.g. "obfs-local"
+	Aliases    []string          // other names of the plugin, e.g. "simple-obfs"
+	KeyAliases map[string]string // other option names to the ones of the program
+
+	options map[string]pluginOption
+}
+
+// pluginSchemas ... Schemas of the common plugins.
+var pluginSchemas = []*PluginSchema{
+	{
+		Name:       "obfs-local",
+		Aliases:    []string{"simple-obfs"},
+		KeyAliases: map[string]string{"mode": "obfs", "host": "obfs-host", "uri": "obfs-uri"},
+		options: map[string]pluginOption{
+			"obfs":      oneOf("http", "tls"),
+			"obfs-host": anyValue,
+			"obfs-uri":  anyValue,
+			"fast-open": flagOption,
+		},
+	},
+	{
+		Name: "v2ray-plugin",
+		options: map[string]pluginOption{
+			"mode":     oneOf("websocket", "quic"),
+			"host":     anyValue,
+			"path":     anyValue,
+			"tls":      flagOption,
+			"mux":      numberValue,
+			"cert":     anyValue,
+			"certRaw":  anyValue,
+			"loglevel": oneOf("debug", "info", "warning", "error", "none"),
+			"fastOpen": flagOption,
+		},
+	},
+	{
+		Name:    "kcptun",
+		Aliases: []string{"kcptun-client"},
+		options: map[string]pluginOption{
+			"key":         anyValue,
+			"crypt":       oneOf("aes", "aes-128", "aes-192", "salsa20", "blowfish", "twofish", "cast5", "3des", "tea", "xtea", "xor", "sm4", "none"),
+			"mode":        oneOf("normal", "fast", "fast2", "fast3", "manual"),
+			"mtu":         numberValue,
+			"sndwnd":      numberValue,
+			"rcvwnd":      numberValue,
+			"datashard":   numberValue,
+			"parityshard": numberValue,
+			"dscp":        numberValue,
+			"conn":        numberValue,
+			"autoexpire":  numberValue,
+			"scavengettl": numberValue,
+			"sockbuf":     numberValue,
+			"smuxver":     numberValue,
+			"smuxbuf":     numberValue,
+			"streambuf":   numberValue,
+			"keepalive":   numberValue,
+			"nodelay":     numberValue,
+			"interval":    numberValue,
+			"resend":      numberValue,
+			"nc":          numberValue,
+			"nocomp":      flagOption,
+			"acknodelay":  flagOption,
+			"quiet":       flagOption,
+			"tcp":         flagOption,
+		},
+	},
+	{
+		Name:    "ck-client",
+		Aliases: []string{"cloak"},
+		options: map[string]pluginOption{
+			"UID":              anyValue,
+			"PublicKey":        anyValue,
+			"ServerName":       anyValue,
+			"ProxyMethod":      anyValue,
+			"EncryptionMethod": oneOf("plain", "aes-gcm", "aes-128-gcm", "aes-256-gcm", "chacha20-poly1305"),
+			"BrowserSig":       oneOf("chrome", "firefox", "safari"),
+			"Transport":        oneOf("direct", "CDN"),
+			"CDNOriginHost":    anyValue,
+			"CDNWsUrlPath":     anyValue,
+			"AlternativeNames": anyValue,
+			"NumConn":          numberValue,
+			"StreamTimeout":    numberValue,
+			"KeepAlive":        numberValue,
+		},
+	},
+}
+
+// LookupPluginSchema ... Returns schema of the plugin called name or one of
+// its aliases, nil if the plugin is unknown.
+func LookupPluginSchema(name string) *PluginSchema {
+	for _, s := range pluginSchemas {
+		if s.Name == name {
+			return s
+		}
+
+		for _, alias := range s.Aliases {
+			if alias == name {
+				return s
+			}
+		}
+	}
+
+	return nil
+}
+
+// PluginOptionError ... Problem of a single plugin option.
+type PluginOptionError struct {
+	Plugin  string
+	Key     string
+	Value   string
+	Unknown bool // the plugin has no such option, otherwise the value is invalid
+}
+
+// Error ... Implements error.
+func (e *PluginOptionError) Error() string {
+	if e.Unknown {
+		return "unknown option " + e.Key + " of plugin " + e.Plugin
+	}
+
+	return "invalid value " + strconv.Quote(e.Value) + " of plugin " + e.Plugin + " option " + e.Key
+}
+
+// Check ... Returns problems of options in key order, option names may be aliases.
+func (s *PluginSchema) Check(options map[string]string) []*PluginOptionError {
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	problems := []*PluginOptionError{}
+
+	for _, k := range keys {
+		v := options[k]
+
+		option, ok := s.options[s.canonicalKey(k)]
+		if !ok {
+			problems = append(problems, &PluginOptionError{Plugin: s.Name, Key: k, Value: v, Unknown: true})
+			continue
+		}
+
+		if !option.accepts(v) {
+			problems = append(problems, &PluginOptionError{Plugin: s.Name, Key: k, Value: v})
+		}
+	}
+
+	return problems
+}
+
+// canonicalKey ... Returns the name the program uses for option key.
+func (s *PluginSchema) canonicalKey(key string) string {
+	if _, ok := s.options[key]; ok {
+		return key
+	}
+
+	if canonical, ok := s.KeyAliases[key]; ok {
+		return canonical
+	}
+
+	return key
+}
+
+// accepts ... Reports whether value is valid for the option.
+func (o pluginOption) accepts(value string) bool {
+	switch {
+	case o.flag:
+		return value == ""
+	case o.number:
+		n, err := strconv.Atoi(value)
+		return err == nil && n >= 0
+	case len(o.values) != 0:
+		for _, v := range o.values {
+			if v == value {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// Validate ... Returns the first problem of the options of a known plugin.
+// Options of unknown plugins are not checked. Decoders accept any options
+// unless DecodeOptions.StrictPlugins is set, the client validates before
+// running the plugin.
+func (plugin *PluginInfo) Validate() error {
+	schema := LookupPluginSchema(plugin.name)
+	if schema == nil {
+		return nil
+	}
+
+	if problems := schema.Check(plugin.options); len(problems) != 0 {
+		return problems[0]
+	}
+
+	return nil
+}
+
+// Normalize ... Returns copy of a known plugin under its schema name with
+// option aliases renamed, e.g. simple-obfs;mode=tls becomes obfs-local;obfs=tls.
+// Unknown plugins are returned unchanged. Decoders keep plugins as written,
+// since the name is the program run, so call it only to convert explicitly.
+func (plugin *PluginInfo) Normalize() *PluginInfo {
+	schema := LookupPluginSchema(plugin.name)
+	if schema == nil {
+		return plugin
+	}
+
+	options := make(map[string]string, len(plugin.options))
+
+	for k, v := range plugin.options {
+		options[schema.canonicalKey(k)] = v
+	}
+
+	return NewPlugin(schema.Name, options)
+}
+
+// SimpleObfsOptions ... Typed options of simple-obfs (obfs-local).
+type SimpleObfsOptions struct {
+	Obfs string // "http" or "tls"
+	Host string
+	URI  string
+}
+
+// ParseSimpleObfsOptions ... Returns options of a simple-obfs plugin under either name.
+func ParseSimpleObfsOptions(plugin *PluginInfo) (*SimpleObfsOptions, error) {
+	options, err := typedOptions(plugin, "obfs-local")
+	if err != nil {
+		return nil, err
+	}
+
+	return &SimpleObfsOptions{
+		Obfs: options["obfs"],
+		Host: options["obfs-host"],
+		URI:  options["obfs-uri"],
+	}, nil
+}
+
+// Plugin ... Returns obfs-local plugin with the options.
+func (o *SimpleObfsOptions) Plugin() *PluginInfo {
+	return NewPlugin("obfs-local", nonEmpty(map[string]string{
+		"obfs":      o.Obfs,
+		"obfs-host": o.Host,
+		"obfs-uri":  o.URI,
+	}))
+}
+
+// V2RayPluginOptions ... Typed options of v2ray-plugin.
+type V2RayPluginOptions struct {
+	Mode string // "websocket" if empty, or "quic"
+	Host string
+	Path string
+	TLS  bool
+	Mux  int // concurrent connections per mux, default if zero
+}
+
+// ParseV2RayPluginOptions ... Returns options of a v2ray-plugin.
+func ParseV2RayPluginOptions(plugin *PluginInfo) (*V2RayPluginOptions, error) {
+	options, err := typedOptions(plugin, "v2ray-plugin")
+	if err != nil {
+		return nil, err
+	}
+
+	_, tls := options["tls"]
+	mux, _ := strconv.Atoi(options["mux"])
+
+	return &V2RayPluginOptions{
+		Mode: options["mode"],
+		Host: options["host"],
+		Path: options["path"],
+		TLS:  tls,
+		Mux:  mux,
+	}, nil
+}
+
+// Plugin ... Returns v2ray-plugin with the options.
+func (o *V2RayPluginOptions) Plugin() *PluginInfo {
+	options := nonEmpty(map[string]string{
+		"mode": o.Mode,
+		"host": o.Host,
+		"path": o.Path,
+	})
+
+	if o.TLS {
+		options["tls"] = ""
+	}
+
+	if o.Mux > 0 {
+		options["mux"] = strconv.Itoa(o.Mux)
+	}
+
+	return NewPlugin("v2ray-plugin", options)
+}
+
+// KcptunOptions ... Typed options of kcptun, the most common ones only.
+type KcptunOptions struct {
+	Key    string
+	Crypt  string // "aes" if empty
+	Mode   string // "fast" if empty
+	MTU    int    // default if zero
+	NoComp bool
+}
+
+// ParseKcptunOptions ... Returns options of a kcptun plugin.
+func ParseKcptunOptions(plugin *PluginInfo) (*KcptunOptions, error) {
+	options, err := typedOptions(plugin, "kcptun")
+	if err != nil {
+		return nil, err
+	}
+
+	_, nocomp := options["nocomp"]
+	mtu, _ := strconv.Atoi(options["mtu"])
+
+	return &KcptunOptions{
+		Key:    options["key"],
+		Crypt:  options["crypt"],
+		Mode:   options["mode"],
+		MTU:    mtu,
+		NoComp: nocomp,
+	}, nil
+}
+
+// Plugin ... Returns kcptun plugin with the options.
+func (o *KcptunOptions) Plugin() *PluginInfo {
+	options := nonEmpty(map[string]string{
+		"key":   o.Key,
+		"crypt": o.Crypt,
+		"mode":  o.Mode,
+	})
+
+	if o.MTU > 0 {
+		options["mtu"] = strconv.Itoa(o.MTU)
+	}
+
+	if o.NoComp {
+		options["nocomp"] = ""
+	}
+
+	return NewPlugin("kcptun", options)
+}
+
+// CloakOptions ... Typed options of Cloak (ck-client), the most common ones only.
+type CloakOptions struct {
+	UID              string
+	PublicKey        string
+	ServerName       string
+	ProxyMethod      string
+	EncryptionMethod string
+	NumConn          int // default if zero
+}
+
+// ParseCloakOptions ... Returns options of a Cloak plugin under either name.
+func ParseCloakOptions(plugin *PluginInfo) (*CloakOptions, error) {
+	options, err := typedOptions(plugin, "ck-client")
+	if err != nil {
+		return nil, err
+	}
+
+	numConn, _ := strconv.Atoi(options["NumConn"])
+
+	return &CloakOptions{
+		UID:              options["UID"],
+		PublicKey:        options["PublicKey"],
+		ServerName:       options["ServerName"],
+		ProxyMethod:      options["ProxyMethod"],
+		EncryptionMethod: options["EncryptionMethod"],
+		NumConn:          numConn,
+	}, nil
+}
+
+// Plugin ... Returns ck-client plugin with the options.
+func (o *CloakOptions) Plugin() *PluginInfo {
+	options := nonEmpty(map[string]string{
+		"UID":              o.UID,
+		"PublicKey":        o.PublicKey,
+		"ServerName":       o.ServerName,
+		"ProxyMethod":      o.ProxyMethod,
+		"EncryptionMethod": o.EncryptionMethod,
+	})
+
+	if o.NumConn > 0 {
+		options["NumConn"] = strconv.Itoa(o.NumConn)
+	}
+
+	return NewPlugin("ck-client", options)
+}
+
+// typedOptions ... Returns validated options of plugin under their canonical
+// names, plugin must be the one called name.
+func typedOptions(plugin *PluginInfo, name string) (map[string]string, error) {
+	if schema := LookupPluginSchema(plugin.name); schema == nil || schema.Name != name {
+		return nil, errors.New("plugin " + plugin.name + " is not " + name)
+	}
+
+	if err := plugin.Validate(); err != nil {
+		return nil, err
+	}
+
+	return plugin.Normalize().options, nil
+}
+
+// nonEmpty ... Returns options without empty values.
+func nonEmpty(options map[string]string) map[string]string {
+	for k, v := range options {
+		if v == "" {
+			delete(options, k)
+		}
+	}
+
+	return options
+}
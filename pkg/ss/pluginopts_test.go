@@ -0,0 +1,206 @@
+package ss_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestPluginValidate(t *testing.T) {
+	tests := []struct {
+		plugin  *ss.PluginInfo
+		problem string // key of the first problem, "" if valid
+		unknown bool
+	}{
+		{ss.NewPlugin("obfs-local", map[string]string{"obfs": "http", "obfs-host": "a.com"}), "", false},
+		{ss.NewPlugin("simple-obfs", map[string]string{"mode": "tls", "host": "a.com"}), "", false},
+		{ss.NewPlugin("obfs-local", map[string]string{"obfs": "websocket"}), "obfs", false},
+		{ss.NewPlugin("obfs-local", map[string]string{"obfs": "http", "path": "/"}), "path", true},
+		{ss.NewPlugin("v2ray-plugin", map[string]string{"tls": "", "host": "a.com", "mux": "8"}), "", false},
+		{ss.NewPlugin("v2ray-plugin", map[string]string{"tls": "true"}), "tls", false},
+		{ss.NewPlugin("v2ray-plugin", map[string]string{"mux": "-1"}), "mux", false},
+		{ss.NewPlugin("kcptun", map[string]string{"crypt": "rot13"}), "crypt", false},
+		{ss.NewPlugin("cloak", map[string]string{"EncryptionMethod": "plain", "NumConn": "4"}), "", false},
+		{ss.NewPlugin("my-plugin", map[string]string{"anything": "goes"}), "", false},
+	}
+
+	for i, ut := range tests {
+		err := ut.plugin.Validate()
+
+		if ut.problem == "" {
+			if err != nil {
+				t.Errorf("#%d test failed. Expected valid, Got: %v", i, err)
+			}
+
+			continue
+		}
+
+		problem, ok := err.(*ss.PluginOptionError)
+		if !ok || problem.Key != ut.problem || problem.Unknown != ut.unknown {
+			t.Errorf("#%d test failed. Expected problem with %s, Got: %v", i, ut.problem, err)
+		}
+	}
+}
+
+func TestPluginNormalize(t *testing.T) {
+	plugin := ss.NewPlugin("simple-obfs", map[string]string{"mode": "tls", "host": "a.com"}).Normalize()
+	expected := ss.NewPlugin("obfs-local", map[string]string{"obfs": "tls", "obfs-host": "a.com"})
+
+	if !plugin.Equal(expected) {
+		t.Errorf("Expected %v, Got: %v", expected, plugin)
+	}
+
+	obfs, err := ss.ParseSimpleObfsOptions(ss.NewPlugin("simple-obfs", map[string]string{"mode": "http", "host": "a.com"}))
+	if err != nil || obfs.Obfs != "http" || obfs.Host != "a.com" || !obfs.Plugin().Equal(ss.NewPlugin("obfs-local", map[string]string{"obfs": "http", "obfs-host": "a.com"})) {
+		t.Errorf("Unexpected simple-obfs options %+v, %v", obfs, err)
+	}
+
+	v2ray, err := ss.ParseV2RayPluginOptions(ss.NewPlugin("v2ray-plugin", map[string]string{"tls": "", "host": "a.com", "path": "/ws", "mux": "4"}))
+	if err != nil || !v2ray.TLS || v2ray.Mux != 4 || v2ray.Plugin().OptionsString() != "host=a.com;mux=4;path=/ws;tls" {
+		t.Errorf("Unexpected v2ray-plugin options %+v, %v", v2ray, err)
+	}
+
+	kcptun, err := ss.ParseKcptunOptions(ss.NewPlugin("kcptun", map[string]string{"key": "k", "crypt": "aes", "nocomp": ""}))
+	if err != nil || !kcptun.NoComp || kcptun.Plugin().OptionsString() != "crypt=aes;key=k;nocomp" {
+		t.Errorf("Unexpected kcptun options %+v, %v", kcptun, err)
+	}
+
+	cloak, err := ss.ParseCloakOptions(ss.NewPlugin("ck-client", map[string]string{"UID": "u", "NumConn": "4"}))
+	if err != nil || cloak.UID != "u" || cloak.NumConn != 4 {
+		t.Errorf("Unexpected Cloak options %+v, %v", cloak, err)
+	}
+
+	if _, err := ss.ParseCloakOptions(expected); err == nil {
+		t.Errorf("Expected error for plugin of another kind")
+	}
+}
+
+func TestDecodePluginLenient(t *testing.T) {
+	// Decoders keep plugins as written, the name is the program to run.
+	uri, err := ss.DecodeSIP002URI("ss://cmM0LW1kNTpwYXNzd2Q=@192.168.100.1:8888/?plugin=simple-obfs%3Bmode%3Dtls#a")
+	if err != nil || uri.Plugin.Name() != "simple-obfs" || uri.Plugin.Options()["mode"] != "tls" {
+		t.Errorf("Expected simple-obfs kept as written, Got: %v, %v", uri, err)
+	}
+
+	uri, err = ss.DecodeSIP002URI("ss://cmM0LW1kNTpwYXNzd2Q=@192.168.100.1:8888/?plugin=v2ray-plugin%3Btls%3Bhost%3Da.com#a")
+	if err != nil || uri.Plugin.OptionsString() != "host=a.com;tls" {
+		t.Errorf("Expected tls flag, Got: %v, %v", uri, err)
+	}
+
+	// Options unknown to the schemas decode, checking them is up to callers.
+	uri, err = ss.DecodeSIP002URI("ss://cmM0LW1kNTpwYXNzd2Q=@192.168.100.1:8888/?plugin=v2ray-plugin%3Bmode%3Dgrpc%3Bserver#a")
+	if err != nil || uri.Plugin.Validate() == nil {
+		t.Errorf("Expected decoded plugin failing Validate, Got: %v, %v", uri, err)
+	}
+
+	scc, err := ss.DecodeJSON([]byte(`{"server": "a.com", "server_port": 8388, "method": "aes-256-gcm", "password": "p",
+		"plugin": "kcptun-client", "plugin_opts": "crypt=rot13"}`))
+	if err != nil || scc.Plugin.Name() != "kcptun-client" || scc.Plugin.Options()["crypt"] != "rot13" {
+		t.Errorf("Expected kcptun-client kept as written, Got: %v, %v", scc, err)
+	}
+
+	if _, err := ss.NewShadowsocksClient(scc); err == nil {
+		t.Errorf("Expected client to reject invalid plugin options")
+	}
+}
+
+func TestDecodePluginStrict(t *testing.T) {
+	const (
+		valid   = "ss://cmM0LW1kNTpwYXNzd2Q=@192.168.100.1:8888/?plugin=simple-obfs%3Bmode%3Dtls#a"
+		invalid = "ss://cmM0LW1kNTpwYXNzd2Q=@192.168.100.1:8888/?plugin=v2ray-plugin%3Bmode%3Dgrpc#b"
+		unknown = "ss://cmM0LW1kNTpwYXNzd2Q=@192.168.100.1:8888/?plugin=my-plugin%3Bany%3Dthing#c"
+		json    = `{"server": "a.com", "server_port": 8388, "method": "aes-256-gcm", "password": "p",
+			"plugin": "kcptun-client", "plugin_opts": "crypt=rot13"}`
+		sip008 = `{"version": 1, "servers": [{"server": "a.com", "server_port": 8388, "method": "aes-256-gcm",
+			"password": "p", "plugin": "obfs-local", "plugin_opts": "obfs=websocket"}]}`
+	)
+
+	strict := &ss.DecodeOptions{StrictPlugins: true}
+
+	tests := []struct {
+		data     string
+		from     string
+		opts     *ss.DecodeOptions
+		expected int // Servers decoded, -1 for an error
+	}{
+		{valid + "\n" + unknown, "uri", strict, 2},
+		{valid + "\n" + invalid, "uri", nil, 2},
+		{valid + "\n" + invalid, "uri", &ss.DecodeOptions{}, 2},
+		{valid + "\n" + invalid, "uri", strict, -1},
+		{invalid, "sip002", strict, -1},
+		{base64.StdEncoding.EncodeToString([]byte(valid + "\n" + invalid)), "subscription", nil, 2},
+		{base64.StdEncoding.EncodeToString([]byte(valid + "\n" + invalid)), "subscription", strict, -1},
+		{json, "client-json", nil, 1},
+		{json, "client-json", strict, -1},
+		{sip008, "sip008", nil, 1},
+		{sip008, "sip008", strict, -1},
+	}
+
+	for i, ut := range tests {
+		uris, err := ss.DecodeFormatWithOptions([]byte(ut.data), ut.from, ut.opts)
+
+		if ut.expected == -1 {
+			if err == nil {
+				t.Errorf("#%d test failed. Expected error, Got: %v", i, uris)
+			}
+
+			continue
+		}
+
+		if err != nil || len(uris) != ut.expected {
+			t.Errorf("#%d test failed. Expected %d servers, Got: %v, %v", i, ut.expected, uris, err)
+		}
+	}
+
+	// Every invalid server is reported, numbered from 1.
+	_, err := ss.DecodeFormatWithOptions([]byte(invalid+"\n"+valid+"\n"+invalid), "uri", strict)
+	if err == nil || !strings.Contains(err.Error(), "server #1: ") || !strings.Contains(err.Error(), "server #3: ") {
+		t.Errorf("Expected servers #1 and #3 reported, Got: %v", err)
+	}
+}
+
+func TestLintDecodedPlugin(t *testing.T) {
+	tests := []struct {
+		uri      string
+		severity []ss.Severity
+		message  []string
+	}{
+		{
+			"ss://YWVzLTI1Ni1nY206Y29ycmVjdCBob3JzZSBiYXR0ZXJ5@example.com:8388/?plugin=v2ray-plugin%3Btls%3BallowInsecure%3Dtrue#a",
+			[]ss.Severity{ss.SeverityError},
+			[]string{"allowInsecure"},
+		},
+		{
+			"ss://YWVzLTI1Ni1nY206Y29ycmVjdCBob3JzZSBiYXR0ZXJ5@example.com:8388/?plugin=v2ray-plugin%3Btls%3Bmode%3Dgrpc%3Bttl%3D5#a",
+			[]ss.Severity{ss.SeverityError, ss.SeverityWarning},
+			[]string{"mode", "ttl"},
+		},
+		{
+			"ss://YWVzLTI1Ni1nY206Y29ycmVjdCBob3JzZSBiYXR0ZXJ5@example.com:8388/?plugin=kcptun-client%3Bcrypt%3Dnone#a",
+			[]ss.Severity{ss.SeverityWarning},
+			[]string{"crypt=none"},
+		},
+	}
+
+	for i, ut := range tests {
+		uri, err := ss.DecodeURI(ut.uri)
+		if err != nil {
+			t.Errorf("#%d test failed. DecodeURI() failed: %v", i, err)
+			continue
+		}
+
+		findings := ss.Lint([]*ss.ShadowsocksURI{uri}, nil)
+		if len(findings) != len(ut.message) {
+			t.Errorf("#%d test failed. Expected %d findings, Got: %v", i, len(ut.message), findings)
+			continue
+		}
+
+		for j, finding := range findings {
+			if finding.Severity != ut.severity[j] || !strings.Contains(finding.Message, ut.message[j]) {
+				t.Errorf("#%d test failed. Expected %v finding about %s, Got: %v", i, ut.severity[j], ut.message[j], finding)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package ss
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ProbeOptions ... Options of Probe.
+type ProbeOptions struct {
+	// Timeout of each step, 5 seconds if zero.
+	Timeout time.Duration
+
+	// Workers is the number of servers probed at once, 8 if zero.
+	Workers int
+
+	// URL is fetched through the tunnel if not empty, which checks the
+	// method and password too, e.g. "http://www.gstatic.com/generate_204".
+	URL string
+}
+
+// ProbeResult ... Outcome of probing one server.
+type ProbeResult struct {
+	Index   int             // Index of server in the probed list
+	URI     *ShadowsocksURI // Probed server
+	Connect time.Duration   // Time of the TCP connection to Remote
+	Latency time.Duration   // Time of the request of URL through the tunnel
+	Status  int             // HTTP status of URL
+	Err     error           // First failure, nil if the server is alive
+}
+
+// Alive ... Reports whether every step of the probe succeeded.
+func (r *ProbeResult) Alive() bool {
+	return r.Err == nil
+}
+
+// Probe ... Check servers concurrently, results are in order of uris.
+func Probe(uris []*ShadowsocksURI, opts *ProbeOptions) []*ProbeResult {
+	if opts == nil {
+		opts = &ProbeOptions{}
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 8
+	}
+
+	results := make([]*ProbeResult, len(uris))
+	indices := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indices {
+				results[i] = probe(i, uris[i], opts)
+			}
+		}()
+	}
+
+	for i := range uris {
+		indices <- i
+	}
+
+	close(indices)
+	wg.Wait()
+
+	return results
+}
+
+// probe ... Probe one server.
+func probe(index int, uri *ShadowsocksURI, opts *ProbeOptions) *ProbeResult {
+	result := &ProbeResult{Index: index, URI: uri}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	start := time.Now()
+
+	conn, err := net.DialTimeout("tcp", dialAddress(uri.Remote), timeout)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Connect = time.Since(start)
+	conn.Close()
+
+	if opts.URL == "" {
+		return result
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return Dial(uri, addr, timeout)
+			},
+			DisableKeepAlives: true,
+		},
+	}
+
+	start = time.Now()
+
+	resp, err := client.Get(opts.URL)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+
+	result.Latency = time.Since(start)
+	result.Status = resp.StatusCode
+
+	return result
+}
@@ -0,0 +1,68 @@
+package ss_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+	"github.com/vgxbj/ssuri/pkg/ss/sstest"
+)
+
+// closedPort ... Returns a local port nobody listens on.
+func closedPort(t *testing.T) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	return port
+}
+
+func TestProbe(t *testing.T) {
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer web.Close()
+
+	good := sstest.NewServer("chacha20-ietf-poly1305", "correct horse")
+	defer good.Close()
+
+	wrong := sstest.NewServer("chacha20-ietf-poly1305", "wrong horse")
+	defer wrong.Close()
+
+	other := sstest.NewServer("aes-128-gcm", "correct horse")
+	defer other.Close()
+
+	uris := []*ss.ShadowsocksURI{
+		good.URI,
+		{Remote: wrong.URI.Remote, Auth: good.URI.Auth},
+		{Remote: ss.NewServer("127.0.0.1", closedPort(t)), Auth: ss.NewAuthInfo("aes-256-gcm", "x")},
+		other.URI,
+	}
+
+	results := ss.Probe(uris, &ss.ProbeOptions{Timeout: 2 * time.Second, Workers: 2, URL: web.URL})
+
+	alive := []bool{true, false, false, true}
+
+	for i, r := range results {
+		if r.Index != i || r.URI != uris[i] || r.Alive() != alive[i] {
+			t.Errorf("#%d test failed. Expected alive: %v, Got: %v", i, alive[i], r.Err)
+		}
+
+		if r.Alive() && (r.Status != http.StatusNoContent || r.Latency == 0) {
+			t.Errorf("#%d test failed. Expected status 204 and latency, Got: %d %v", i, r.Status, r.Latency)
+		}
+	}
+
+	// Without URL only the TCP connection is checked.
+	results = ss.Probe(uris[1:3], nil)
+	if !results[0].Alive() || results[0].Connect == 0 || results[1].Alive() {
+		t.Errorf("Unexpected results: %v %v", results[0].Err, results[1].Err)
+	}
+}
@@ -0,0 +1,150 @@
+package ss
+
+import (
+	"errors"
+	"strings"
+)
+
+// RedactMode ... How passwords and other secrets are masked in output.
+type RedactMode int
+
+const (
+	// RedactNone ... Keep secrets in clear text.
+	RedactNone RedactMode = iota
+	// RedactPartial ... Keep first and last character of long secrets, e.g. "t****t".
+	RedactPartial
+	// RedactFull ... Replace secrets with RedactMask.
+	RedactFull
+)
+
+// RedactMask ... Replacement of a fully redacted secret, independent of its length.
+const RedactMask = "********"
+
+var redactModeNames = []string{"none", "partial", "full"}
+
+// String ... Returns name of redaction mode.
+func (m RedactMode) String() string {
+	if m < 0 || int(m) >= len(redactModeNames) {
+		return "unknown"
+	}
+
+	return redactModeNames[m]
+}
+
+// ParseRedactMode ... Parse redaction mode by its name.
+func ParseRedactMode(s string) (RedactMode, error) {
+	for i, name := range redactModeNames {
+		if name == s {
+			return RedactMode(i), nil
+		}
+	}
+
+	return RedactNone, errors.New("invalid redaction mode: " + s)
+}
+
+// secretPluginOptions ... Plugin options holding keys or credentials, in lower case.
+var secretPluginOptions = map[string]bool{
+	"key": true, "password": true, "passwd": true, "psk": true, "secret": true,
+	"token": true, "uid": true, "privatekey": true,
+}
+
+// RedactSecret ... Mask secret according to mode. Partial redaction keeps the
+// first and last character of secrets of at least 8 characters only.
+func RedactSecret(secret string, mode RedactMode) string {
+	switch mode {
+	case RedactNone:
+		return secret
+	case RedactPartial:
+		runes := []rune(secret)
+		if len(runes) >= 8 {
+			return string(runes[0]) + "****" + string(runes[len(runes)-1])
+		}
+	}
+
+	return RedactMask
+}
+
+// redactPlugin ... Returns copy of plugin with secret options masked.
+func redactPlugin(plugin *PluginInfo, mode RedactMode) *PluginInfo {
+	if plugin == nil {
+		return nil
+	}
+
+	options := make(map[string]string, len(plugin.options))
+
+	for k, v := range plugin.options {
+		if secretPluginOptions[strings.ToLower(k)] {
+			v = RedactSecret(v, mode)
+		}
+
+		options[k] = v
+	}
+
+	return NewPlugin(plugin.name, options)
+}
+
+// Redact ... Returns copy of shadowsocks URI with password and plugin secrets masked.
+func (uri *ShadowsocksURI) Redact(mode RedactMode) *ShadowsocksURI {
+	c := uri.Copy()
+	if c == nil || mode == RedactNone {
+		return c
+	}
+
+	if c.Auth != nil {
+		c.Auth = NewAuthInfo(c.Auth.method, RedactSecret(c.Auth.password, mode))
+	}
+
+	c.Plugin = redactPlugin(c.Plugin, mode)
+
+	return c
+}
+
+// StripSecrets ... Returns copy of shadowsocks URI with an empty password and
+// without secret plugin options. Unlike Redact, the encoded URI carries no
+// trace of the secrets, so it can be shared but not used as it is: decoders
+// reject URIs without password.
+func (uri *ShadowsocksURI) StripSecrets() *ShadowsocksURI {
+	c := uri.Copy()
+	if c == nil {
+		return nil
+	}
+
+	if c.Auth != nil {
+		c.Auth = NewAuthInfo(c.Auth.method, "")
+	}
+
+	if c.Plugin != nil {
+		options := make(map[string]string, len(c.Plugin.options))
+
+		for k, v := range c.Plugin.options {
+			if !secretPluginOptions[strings.ToLower(k)] {
+				options[k] = v
+			}
+		}
+
+		c.Plugin = NewPlugin(c.Plugin.name, options)
+	}
+
+	return c
+}
+
+// Redact ... Returns copy of client configuration with password and plugin secrets masked.
+// Extras are shared with scc.
+func (scc *ShadowsocksClientConfig) Redact(mode RedactMode) *ShadowsocksClientConfig {
+	if scc == nil {
+		return nil
+	}
+
+	c := *scc
+	if mode == RedactNone {
+		return &c
+	}
+
+	if c.Auth != nil {
+		c.Auth = NewAuthInfo(c.Auth.method, RedactSecret(c.Auth.password, mode))
+	}
+
+	c.Plugin = redactPlugin(c.Plugin, mode)
+
+	return &c
+}
@@ -0,0 +1,84 @@
+package ss_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestRedact(t *testing.T) {
+	uri := &ss.ShadowsocksURI{
+		Remote: ss.NewServer("192.168.100.1", 8888),
+		Auth:   ss.NewAuthInfo("aes-256-gcm", "correct horse"),
+		Tag:    "example-server",
+		Plugin: ss.NewPlugin("kcptun", map[string]string{"key": "it's a secret", "crypt": "aes"}),
+	}
+
+	tests := []struct {
+		mode     ss.RedactMode
+		password string
+		key      string
+	}{
+		{ss.RedactNone, "correct horse", "it's a secret"},
+		{ss.RedactPartial, "c****e", "i****t"},
+		{ss.RedactFull, ss.RedactMask, ss.RedactMask},
+	}
+
+	for i, ut := range tests {
+		redacted := uri.Redact(ut.mode)
+
+		if redacted.Auth.Password() != ut.password || redacted.Plugin.Options()["key"] != ut.key {
+			t.Errorf("#%d test failed. Expected: %v %v, Got: %v", i, ut.password, ut.key, redacted)
+		}
+
+		if redacted.Plugin.Options()["crypt"] != "aes" || redacted.Tag != uri.Tag {
+			t.Errorf("#%d test failed. Expected other fields untouched, Got: %v", i, redacted)
+		}
+
+		scc := ss.ToShadowsocksClientConfig(uri).Redact(ut.mode)
+		if scc.Auth.Password() != ut.password || scc.Plugin.Options()["key"] != ut.key {
+			t.Errorf("#%d test failed. Expected: %v %v, Got: %v", i, ut.password, ut.key, *scc)
+		}
+	}
+
+	if uri.Auth.Password() != "correct horse" || uri.Plugin.Options()["key"] != "it's a secret" {
+		t.Errorf("Expected original to be untouched, Got: %v", uri)
+	}
+
+	// Short secrets are masked fully even in partial mode.
+	if s := ss.RedactSecret("test", ss.RedactPartial); s != ss.RedactMask {
+		t.Errorf("Expected: %v, Got: %v", ss.RedactMask, s)
+	}
+
+	// Redacted URIs do not leak the password in any encoding.
+	redacted := uri.Redact(ss.RedactFull)
+	for _, encoded := range []string{redacted.EncodeSIP002URI(), redacted.EncodeBase64URI(), redacted.EncodePlainURI()} {
+		decoded, err := ss.DecodeURI(encoded)
+		if err != nil || strings.Contains(encoded, "correct") || decoded.Auth.Password() != ss.RedactMask {
+			t.Errorf("Expected redacted URI, Got: %v, %v", encoded, err)
+		}
+	}
+
+	// Stripped URIs carry neither the password nor secret plugin options.
+	stripped := uri.StripSecrets()
+	for i, ut := range []struct {
+		encoded  string
+		expected string
+	}{
+		{stripped.EncodeSIP002URI(), "ss://YWVzLTI1Ni1nY206@192.168.100.1:8888/?plugin=kcptun%3Bcrypt%3Daes#example-server"},
+		{stripped.EncodePlainURI(), "ss://aes-256-gcm:@192.168.100.1:8888"},
+	} {
+		if ut.encoded != ut.expected {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v", i, ut.expected, ut.encoded)
+		}
+	}
+
+	if uri.Auth.Password() != "correct horse" || uri.Plugin.Options()["key"] != "it's a secret" {
+		t.Errorf("Expected original to be untouched, Got: %v", uri)
+	}
+
+	if _, err := ss.ParseRedactMode("partly"); err == nil {
+		t.Errorf("Expected error for invalid redaction mode")
+	}
+}
@@ -0,0 +1,129 @@
+package ss
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// RenameData ... Fields available to rename templates.
+type RenameData struct {
+	Index    int    // 1-based position in the list
+	Tag      string // Current tag
+	Country  string // Upper-case ISO 3166 code guessed from Tag, may be empty
+	Hostname string
+	Port     int
+	Method   string
+	Plugin   string // Plugin name, may be empty
+}
+
+// Rename ... Rewrite tags of servers in place using a text/template,
+// e.g. "{{.Country}}-{{.Index}}". See RenameData for available fields.
+func Rename(uris []*ShadowsocksURI, text string) error {
+	tags, err := ExecuteTemplate(uris, text)
+	if err != nil {
+		return err
+	}
+
+	// Only touch the list if every tag was generated.
+	for i, uri := range uris {
+		uri.Tag = tags[i]
+	}
+
+	return nil
+}
+
+// ExecuteTemplate ... Execute a text/template once per server, see RenameData for available fields.
+func ExecuteTemplate(uris []*ShadowsocksURI, text string) ([]string, error) {
+	tmpl, err := parseTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]string, len(uris))
+
+	for i, uri := range uris {
+		var buf bytes.Buffer
+
+		if err := tmpl.Execute(&buf, newRenameData(i+1, uri)); err != nil {
+			return nil, err
+		}
+
+		results[i] = buf.String()
+	}
+
+	return results, nil
+}
+
+// CheckTemplate ... Parse a rename template and execute it once on sample
+// data, so that unknown fields are reported before any server is renamed.
+func CheckTemplate(text string) error {
+	tmpl, err := parseTemplate(text)
+	if err != nil {
+		return err
+	}
+
+	sample := &RenameData{
+		Index:    1,
+		Tag:      "HK-01",
+		Country:  "HK",
+		Hostname: "example.com",
+		Port:     8388,
+		Method:   "aes-256-gcm",
+		Plugin:   "obfs-local",
+	}
+
+	return tmpl.Execute(ioutil.Discard, sample)
+}
+
+// parseTemplate ... Parse a rename template.
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("tag").Option("missingkey=error").Parse(text)
+}
+
+// newRenameData ... Collect template fields of server.
+func newRenameData(index int, uri *ShadowsocksURI) *RenameData {
+	return &RenameData{
+		Index:    index,
+		Tag:      uri.Tag,
+		Country:  guessCountry(uri.Tag),
+		Hostname: uri.Remote.Hostname(),
+		Port:     uri.Remote.Port(),
+		Method:   uri.Auth.Method(),
+		Plugin:   pluginName(uri),
+	}
+}
+
+// guessCountry ... Guess country code from tag.
+// A flag emoji anywhere in the tag wins, otherwise a leading two-letter word
+// such as "HK" in "HK-01" or "us west" is used.
+func guessCountry(tag string) string {
+	runes := []rune(tag)
+
+	for i := 0; i+1 < len(runes); i++ {
+		if isRegionalIndicator(runes[i]) && isRegionalIndicator(runes[i+1]) {
+			return string([]rune{runes[i] - 0x1F1E6 + 'A', runes[i+1] - 0x1F1E6 + 'A'})
+		}
+	}
+
+	word := strings.FieldsFunc(tag, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(word) > 0 && len(word[0]) == 2 {
+		code := []rune(word[0])
+		if code[0] <= unicode.MaxASCII && unicode.IsLetter(code[0]) &&
+			code[1] <= unicode.MaxASCII && unicode.IsLetter(code[1]) {
+			return strings.ToUpper(word[0])
+		}
+	}
+
+	return ""
+}
+
+// isRegionalIndicator ... Reports whether r is a regional indicator symbol (flag emoji half).
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
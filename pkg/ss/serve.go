@@ -0,0 +1,147 @@
+package ss
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+// SubscriptionHandler ... HTTP handler publishing servers for onboarding:
+//
+//	/             HTML page listing servers with their QR codes
+//	/sub          base64 subscription, see EncodeSubscription()
+//	/sip008.json  SIP008 document, see EncodeSIP008()
+//	/qr/<n>.png   QR code of the SIP002 URI of the n-th server, from 1
+//
+// With a token, every path is served below /<token>/ and anything else is
+// answered with 404, so the URL itself is the credential.
+type SubscriptionHandler struct {
+	uris  []*ShadowsocksURI
+	token string
+}
+
+// NewSubscriptionHandler ... Returns handler serving uris, token may be empty.
+func NewSubscriptionHandler(uris []*ShadowsocksURI, token string) *SubscriptionHandler {
+	return &SubscriptionHandler{uris: uris, token: token}
+}
+
+// ServeHTTP ... Implements http.Handler.
+func (h *SubscriptionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path, ok := h.stripToken(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Servers include passwords, keep them out of shared caches.
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch {
+	case path == "/":
+		h.serveIndex(w)
+	case path == "/sub":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(EncodeSubscription(h.uris) + "\n"))
+	case path == "/sip008.json":
+		data, err := EncodeSIP008(h.uris)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	case strings.HasPrefix(path, "/qr/") && strings.HasSuffix(path, ".png"):
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, "/qr/"), ".png"))
+		if err != nil || n < 1 || n > len(h.uris) {
+			http.NotFound(w, r)
+			return
+		}
+
+		code, err := qr.Encode(h.uris[n-1].EncodeSIP002URI(), qr.M)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(code.PNG())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// stripToken ... Returns path below the token prefix, and false if the token does not match.
+func (h *SubscriptionHandler) stripToken(path string) (string, bool) {
+	if h.token == "" {
+		return path, true
+	}
+
+	prefix := "/" + h.token + "/"
+	if len(path) < len(prefix) || subtle.ConstantTimeCompare([]byte(path[:len(prefix)]), []byte(prefix)) != 1 {
+		return "", false
+	}
+
+	return path[len(prefix)-1:], true
+}
+
+// indexServer ... One server of the index page.
+type indexServer struct {
+	Index int
+	Tag   string
+	URI   string
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Shadowsocks servers</title>
+</head>
+<body>
+<h1>Shadowsocks servers</h1>
+<p>Subscribe with <a href="sub">sub</a> (base64) or <a href="sip008.json">sip008.json</a> (SIP008).</p>
+{{- range .}}
+<div>
+<h2>{{.Index}}. {{.Tag}}</h2>
+<img src="qr/{{.Index}}.png" alt="QR code of {{.Tag}}">
+<p><code>{{.URI}}</code></p>
+</div>
+{{- end}}
+</body>
+</html>
+`))
+
+// serveIndex ... Write the HTML page. Links are relative, so they work below the token prefix.
+func (h *SubscriptionHandler) serveIndex(w http.ResponseWriter) {
+	servers := make([]*indexServer, len(h.uris))
+
+	for i, uri := range h.uris {
+		tag := uri.Tag
+		if tag == "" {
+			tag = uri.Remote.String()
+		}
+
+		servers[i] = &indexServer{Index: i + 1, Tag: tag, URI: uri.EncodeSIP002URI()}
+	}
+
+	var page bytes.Buffer
+	if err := indexTemplate.Execute(&page, servers); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page.Bytes())
+}
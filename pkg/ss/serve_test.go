@@ -0,0 +1,90 @@
+package ss_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func get(t *testing.T, url string) (int, string, []byte) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return resp.StatusCode, resp.Header.Get("Content-Type"), body
+}
+
+func TestSubscriptionHandler(t *testing.T) {
+	uris := newTestList()
+
+	server := httptest.NewServer(ss.NewSubscriptionHandler(uris, "s3cret"))
+	defer server.Close()
+
+	base := server.URL + "/s3cret"
+
+	code, _, body := get(t, base+"/sub")
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, Got: %d", code)
+	}
+
+	decoded, err := ss.DecodeSubscription(string(body))
+	if err != nil || len(decoded) != len(uris) || !decoded[1].Equal(uris[1]) {
+		t.Errorf("Unexpected subscription: %v, %v", decoded, err)
+	}
+
+	code, contentType, body := get(t, base+"/sip008.json")
+	if code != http.StatusOK || contentType != "application/json" {
+		t.Errorf("Expected JSON, Got: %d %v", code, contentType)
+	}
+
+	if decoded, err := ss.DecodeSIP008(body); err != nil || len(decoded) != len(uris) {
+		t.Errorf("Unexpected SIP008 document: %v, %v", decoded, err)
+	}
+
+	code, contentType, body = get(t, base+"/qr/2.png")
+	if code != http.StatusOK || contentType != "image/png" || !bytes.HasPrefix(body, []byte("\x89PNG")) {
+		t.Errorf("Expected PNG, Got: %d %v", code, contentType)
+	}
+
+	code, _, body = get(t, base+"/")
+	if code != http.StatusOK || !strings.Contains(string(body), `src="qr/4.png"`) || !strings.Contains(string(body), "HK-02") {
+		t.Errorf("Unexpected index: %d\n%s", code, body)
+	}
+
+	for _, path := range []string{"/sub", "/s3cre/sub", "/s3cretx/sub", "/s3cret/qr/0.png", "/s3cret/qr/5.png", "/s3cret/nope"} {
+		if code, _, _ := get(t, server.URL+path); code != http.StatusNotFound {
+			t.Errorf("Expected 404 for %v, Got: %d", path, code)
+		}
+	}
+
+	resp, err := http.Post(base+"/sub", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, Got: %d", resp.StatusCode)
+	}
+
+	open := httptest.NewServer(ss.NewSubscriptionHandler(uris, ""))
+	defer open.Close()
+
+	if code, _, _ := get(t, open.URL+"/sub"); code != http.StatusOK {
+		t.Errorf("Expected 200 without token, Got: %d", code)
+	}
+}
@@ -0,0 +1,116 @@
+package ss
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// ShadowsocksServer ... Minimal shadowsocks server relaying TCP connections and
+// UDP datagrams to their targets, like ssserver. Meant for tests, not for
+// production use.
+type ShadowsocksServer struct {
+	uri       *ShadowsocksURI
+	cipher    *AEADCipher
+	listeners listenerGroup
+
+	// Timeout of the handshake and of connections to targets, 30 seconds if zero.
+	Timeout time.Duration
+
+	// Mode selects what ListenAndServe relays, ModeTCPOnly if empty.
+	Mode string
+}
+
+// NewShadowsocksServer ... Returns server with the method and password of uri.
+// Only AEAD methods are supported, plugins are ignored.
+func NewShadowsocksServer(uri *ShadowsocksURI) (*ShadowsocksServer, error) {
+	c, err := NewAEADCipher(uri.Auth.Method(), uri.Auth.Password())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ShadowsocksServer{uri: uri, cipher: c}, nil
+}
+
+// ListenAndServe ... Listen on host and the port of the server URI, e.g. on
+// "127.0.0.1" for local tests or "" for every interface. TCP and UDP are
+// served as selected by Mode.
+func (s *ShadowsocksServer) ListenAndServe(host string) error {
+	if err := checkMode(s.Mode); err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(s.uri.Remote.Port()))
+
+	var ln net.Listener
+	var pc net.PacketConn
+	var err error
+
+	if relaysTCP(s.Mode) {
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			return err
+		}
+	}
+
+	if relaysUDP(s.Mode) {
+		if pc, err = net.ListenPacket("udp", addr); err != nil {
+			if ln != nil {
+				ln.Close()
+			}
+
+			return err
+		}
+	}
+
+	if ln == nil {
+		return s.ServePacket(pc)
+	}
+
+	if pc != nil {
+		go s.ServePacket(pc)
+	}
+
+	return s.Serve(ln)
+}
+
+// Serve ... Serve shadowsocks on ln until Close is called, ln is closed by Close.
+func (s *ShadowsocksServer) Serve(ln net.Listener) error {
+	return s.listeners.serve(ln, s.serveConn)
+}
+
+// Close ... Stop accepting connections. Open tunnels are not interrupted.
+func (s *ShadowsocksServer) Close() error {
+	return s.listeners.close()
+}
+
+// timeout ... Returns Timeout or its default.
+func (s *ShadowsocksServer) timeout() time.Duration {
+	if s.Timeout <= 0 {
+		return 30 * time.Second
+	}
+
+	return s.Timeout
+}
+
+// serveConn ... Handle one shadowsocks connection.
+func (s *ShadowsocksServer) serveConn(raw net.Conn) {
+	defer raw.Close()
+
+	raw.SetDeadline(time.Now().Add(s.timeout()))
+
+	conn := s.cipher.StreamConn(raw)
+
+	target, err := ReadSocksAddr(conn)
+	if err != nil {
+		return
+	}
+
+	remote, err := net.DialTimeout("tcp", target, s.timeout())
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+
+	raw.SetDeadline(time.Time{})
+	relay(conn, remote)
+}
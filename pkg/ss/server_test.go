@@ -0,0 +1,97 @@
+package ss_test
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+	"github.com/vgxbj/ssuri/pkg/ss/sstest"
+)
+
+func TestShadowsocksServer(t *testing.T) {
+	// Echo server as target.
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer echo.Close()
+
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	// A fixed port, as given by the URI.
+	uri := &ss.ShadowsocksURI{Remote: ss.NewServer("127.0.0.1", closedPort(t)), Auth: ss.NewAuthInfo("aes-128-gcm", "correct horse")}
+
+	server := sstest.StartServer(uri)
+	if server.URI.Remote.Port() != uri.Remote.Port() {
+		t.Errorf("Expected port %d, Got: %d", uri.Remote.Port(), server.URI.Remote.Port())
+	}
+
+	conn, err := ss.Dial(server.URI, echo.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	conn.Write([]byte("ping"))
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("Expected echo, Got: %q, %v", buf, err)
+	}
+
+	conn.Close()
+
+	// A wrong password is dropped.
+	wrong := &ss.ShadowsocksURI{Remote: server.URI.Remote, Auth: ss.NewAuthInfo("aes-128-gcm", "wrong horse")}
+
+	conn, err = ss.Dial(wrong, echo.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	conn.Write([]byte("ping"))
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := io.ReadFull(conn, buf); err == nil {
+		t.Errorf("Expected error for wrong password")
+	}
+
+	conn.Close()
+
+	// Close stops Serve.
+	s, _ := ss.NewShadowsocksServer(uri)
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	done := make(chan error)
+
+	go func() { done <- s.Serve(ln) }()
+
+	s.Close()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Expected error from Serve after Close")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Serve did not return after Close")
+	}
+
+	server.Close()
+
+	if _, err := ss.NewShadowsocksServer(&ss.ShadowsocksURI{Remote: uri.Remote, Auth: ss.NewAuthInfo("bf-cfb", "x")}); err == nil {
+		t.Errorf("Expected error for stream method")
+	}
+}
@@ -0,0 +1,134 @@
+package ss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// SIP008Document ... Online configuration delivery document, see SIP008.
+type SIP008Document struct {
+	Version int             `json:"version"`
+	Servers []*SIP008Server `json:"servers"`
+}
+
+// SIP008Server ... One server of a SIP008 document.
+type SIP008Server struct {
+	ID         string `json:"id"`
+	Remarks    string `json:"remarks,omitempty"`
+	Server     string `json:"server"`
+	ServerPort int    `json:"server_port"`
+	Password   string `json:"password"`
+	Method     string `json:"method"`
+	Plugin     string `json:"plugin,omitempty"`
+	PluginOpts string `json:"plugin_opts,omitempty"`
+}
+
+// NewSIP008Document ... Returns SIP008 document of servers. Server IDs are
+// derived from Fingerprint(), so they stay stable across exports. Servers
+// differing only in tag or plugin get IDs derived from their position among
+// them, keeping IDs unique.
+func NewSIP008Document(uris []*ShadowsocksURI) *SIP008Document {
+	doc := &SIP008Document{Version: 1, Servers: []*SIP008Server{}}
+	ids := make(map[string]bool)
+
+	for _, uri := range uris {
+		fp := uri.Fingerprint()
+		id := fingerprintUUID(fp)
+
+		for n := 2; ids[id]; n++ {
+			sum := sha256.Sum256([]byte(fp + "#" + strconv.Itoa(n)))
+			id = fingerprintUUID(hex.EncodeToString(sum[:]))
+		}
+
+		ids[id] = true
+
+		server := &SIP008Server{
+			ID:         id,
+			Remarks:    uri.Tag,
+			Server:     uri.Remote.Hostname(),
+			ServerPort: uri.Remote.Port(),
+			Password:   uri.Auth.Password(),
+			Method:     uri.Auth.Method(),
+		}
+
+		if uri.Plugin != nil {
+			server.Plugin = uri.Plugin.Name()
+			server.PluginOpts = uri.Plugin.OptionsString()
+		}
+
+		doc.Servers = append(doc.Servers, server)
+	}
+
+	return doc
+}
+
+// EncodeSIP008 ... Encode servers as SIP008 JSON document.
+func EncodeSIP008(uris []*ShadowsocksURI) ([]byte, error) {
+	data, err := json.MarshalIndent(NewSIP008Document(uris), "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// DecodeSIP008 ... Decode servers of a SIP008 JSON document.
+func DecodeSIP008(data []byte) ([]*ShadowsocksURI, error) {
+	var doc SIP008Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Version != 1 {
+		return nil, errors.New("unsupported SIP008 version")
+	}
+
+	uris := []*ShadowsocksURI{}
+
+	for _, server := range doc.Servers {
+		if server.Server == "" || server.ServerPort <= 0 || server.ServerPort > 65535 || server.Method == "" {
+			return nil, errors.New("invalid SIP008 server " + server.ID)
+		}
+
+		uri := &ShadowsocksURI{
+			Remote: NewServer(server.Server, server.ServerPort),
+			Auth:   NewAuthInfo(server.Method, server.Password),
+			Tag:    server.Remarks,
+		}
+
+		if server.Plugin != "" {
+			options, err := ParsePluginOpts(server.PluginOpts)
+			if err != nil {
+				return nil, err
+			}
+
+			uri.Plugin = NewPlugin(server.Plugin, options)
+		}
+
+		uris = append(uris, uri)
+	}
+
+	return uris, nil
+}
+
+// fingerprintUUID ... Format the first 128 bits of a hex fingerprint as an
+// RFC 9562 version 8 (custom) UUID.
+func fingerprintUUID(fp string) string {
+	b := []byte(fp[:32])
+	b[12] = '8'
+	b[16] = "89ab"[fromHexDigit(b[16])&3]
+
+	return string(b[0:8]) + "-" + string(b[8:12]) + "-" + string(b[12:16]) + "-" + string(b[16:20]) + "-" + string(b[20:32])
+}
+
+// fromHexDigit ... Value of a lower case hex digit.
+func fromHexDigit(c byte) byte {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+
+	return c - '0'
+}
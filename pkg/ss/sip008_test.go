@@ -0,0 +1,70 @@
+package ss_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestSIP008(t *testing.T) {
+	uris := newTestList()
+
+	data, err := ss.EncodeSIP008(uris)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	decoded, err := ss.DecodeSIP008(data)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(decoded) != len(uris) {
+		t.Fatalf("Expected %d servers, Got: %d", len(uris), len(decoded))
+	}
+
+	for i := range uris {
+		if !decoded[i].Equal(uris[i]) {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v", i, uris[i], decoded[i])
+		}
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	doc := ss.NewSIP008Document(uris)
+
+	for i, server := range doc.Servers {
+		if !uuid.MatchString(server.ID) {
+			t.Errorf("#%d test failed. Invalid UUID: %v", i, server.ID)
+		}
+	}
+
+	// IDs are stable.
+	if again := ss.NewSIP008Document(uris); again.Servers[0].ID != doc.Servers[0].ID {
+		t.Errorf("Expected stable ID, Got: %v and %v", doc.Servers[0].ID, again.Servers[0].ID)
+	}
+
+	// Servers differing only in tag or plugin get unique IDs.
+	same := []*ss.ShadowsocksURI{uris[0].Copy(), uris[0].Copy(), uris[0].Copy(), uris[0].Copy()}
+	same[1].Tag = "other"
+	same[2].Plugin = ss.NewPlugin("obfs-local", map[string]string{"obfs": "http"})
+
+	ids := make(map[string]bool)
+	for i, server := range ss.NewSIP008Document(same).Servers {
+		if ids[server.ID] || !uuid.MatchString(server.ID) {
+			t.Errorf("#%d test failed. Duplicate or invalid ID: %v", i, server.ID)
+		}
+
+		ids[server.ID] = true
+	}
+
+	if doc := ss.NewSIP008Document(same); doc.Servers[0].ID != ss.NewSIP008Document(uris[:1]).Servers[0].ID {
+		t.Errorf("Expected ID of first server unchanged, Got: %v", doc.Servers[0].ID)
+	}
+
+	for _, data := range []string{`{"version": 2, "servers": []}`, `{"version": 1, "servers": [{"id": "x"}]}`, `[]`} {
+		if _, err := ss.DecodeSIP008([]byte(data)); err == nil {
+			t.Errorf("Expected error for %v", data)
+		}
+	}
+}
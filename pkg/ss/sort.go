@@ -0,0 +1,78 @@
+package ss
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// sortKeys ... Comparators of sortable fields, returning <0, 0 or >0.
+var sortKeys = map[string]func(a, b *ShadowsocksURI) int{
+	"tag": func(a, b *ShadowsocksURI) int {
+		return strings.Compare(a.Tag, b.Tag)
+	},
+	"host": func(a, b *ShadowsocksURI) int {
+		return strings.Compare(normalizeHostname(a.Remote.Hostname()), normalizeHostname(b.Remote.Hostname()))
+	},
+	"port": func(a, b *ShadowsocksURI) int {
+		return a.Remote.Port() - b.Remote.Port()
+	},
+	"method": func(a, b *ShadowsocksURI) int {
+		return strings.Compare(strings.ToLower(a.Auth.Method()), strings.ToLower(b.Auth.Method()))
+	},
+	"password": func(a, b *ShadowsocksURI) int {
+		return strings.Compare(a.Auth.Password(), b.Auth.Password())
+	},
+	"plugin": func(a, b *ShadowsocksURI) int {
+		return strings.Compare(pluginName(a), pluginName(b))
+	},
+}
+
+// SortBy ... Stable sort servers in place by one or more fields.
+// Fields are tag, host, port, method, password and plugin; prefix a field with
+// "-" for descending order. Later fields break ties of earlier ones.
+func SortBy(uris []*ShadowsocksURI, fields ...string) error {
+	type key struct {
+		cmp  func(a, b *ShadowsocksURI) int
+		desc bool
+	}
+
+	keys := []key{}
+
+	for _, field := range fields {
+		desc := strings.HasPrefix(field, "-")
+
+		cmp, ok := sortKeys[strings.TrimPrefix(field, "-")]
+		if !ok {
+			return errors.New("invalid sort field: " + field)
+		}
+
+		keys = append(keys, key{cmp, desc})
+	}
+
+	sort.SliceStable(uris, func(i, j int) bool {
+		for _, k := range keys {
+			c := k.cmp(uris[i], uris[j])
+			if k.desc {
+				c = -c
+			}
+
+			if c != 0 {
+				return c < 0
+			}
+		}
+
+		return false
+	})
+
+	return nil
+}
+
+// pluginName ... Returns plugin name, "" if there is no plugin.
+func pluginName(uri *ShadowsocksURI) string {
+	if uri.Plugin == nil {
+		return ""
+	}
+
+	return uri.Plugin.Name()
+}
@@ -1,6 +1,7 @@
 package ss_test
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/vgxbj/ssuri/pkg/ss"
@@ -80,6 +81,31 @@ func TestURIDecode(t *testing.T) {
 	}
 }
 
+func TestDecodeTag(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected string
+	}{
+		{"example-server", "example-server"},
+		{"HK%2001", "HK 01"},
+		{"50%25", "50%"},
+		{"100%", "100%"},
+		{"%zz", "%zz"},
+		{"%F0%9F%87%AF%F0%9F%87%B5", "\U0001F1EF\U0001F1F5"},
+		{"[::1]:443", "[::1]:443"},
+		{"a#b", "a#b"},
+	}
+
+	for i, ut := range tests {
+		for _, prefix := range []string{"ss://YmYtY2ZiOnRlc3Q=@192.168.100.1:8888", "ss://YmYtY2ZiOnRlc3RAMTkyLjE2OC4xMDAuMTo4ODg4", "ss://bf-cfb:test@192.168.100.1:8888"} {
+			uri, err := ss.DecodeURI(prefix + "#" + ut.tag)
+			if err != nil || uri.Tag != ut.expected {
+				t.Errorf("#%d test failed. Expected: %q, Got: %v, %v", i, ut.expected, uri, err)
+			}
+		}
+	}
+}
+
 func checkBasic(uri1, uri2 *ss.ShadowsocksURI) bool {
 	s1 := uri1.Remote
 	s2 := uri2.Remote
@@ -158,3 +184,73 @@ func checkPlainURI(uri1, uri2 *ss.ShadowsocksURI) bool {
 
 	return true
 }
+
+// nastyPasswords ... Passwords with characters reserved in URIs, escapes and
+// other troublemakers.
+var nastyPasswords = []string{
+	"p@ss", "p@ss@", "@", "p#ss", "#", "##tag", "p:ss", ":", "::1",
+	"%", "%41", "%%", "%zz", "100%", "a/b?c=d&e", "/", "?",
+	" leading", "trailing ", "in side", "\t", "\n",
+	"\\", `\;=`, ";", "=", "+", "a+b", "'\"`", "<>", "[::1]:8388",
+	"ss://m:p@h:1#t", "パスワード", "\U0001F511", "@#:%/?[]",
+}
+
+func TestReservedPasswords(t *testing.T) {
+	for i, password := range nastyPasswords {
+		uri := &ss.ShadowsocksURI{
+			Remote: ss.NewServer("example.com", 8388),
+			Auth:   ss.NewAuthInfo("chacha20-ietf-poly1305", password),
+			Tag:    "tag #" + strconv.Itoa(i),
+		}
+
+		tests := []struct {
+			flavor  string
+			encoded string
+			decode  func(string) (*ss.ShadowsocksURI, error)
+			check   func(uri1, uri2 *ss.ShadowsocksURI) bool
+		}{
+			{"sip002", uri.EncodeSIP002URI(), ss.DecodeSIP002URI, checkSIP002URI},
+			{"base64", uri.EncodeBase64URI(), ss.DecodeBase64URI, checkBase64EncodedURI},
+			{"plain", uri.EncodePlainURI(), ss.DecodePlainURI, checkPlainURI},
+		}
+
+		for _, ut := range tests {
+			for _, decode := range []func(string) (*ss.ShadowsocksURI, error){ut.decode, ss.DecodeURI} {
+				decoded, err := decode(ut.encoded)
+				if err != nil || !ut.check(decoded, uri) {
+					t.Errorf("#%d %s test failed for %q. Encoded: %s, Got: %v, %v", i, ut.flavor, password, ut.encoded, decoded, err)
+				}
+			}
+		}
+
+		list, err := ss.DecodeURIList(uri.EncodePlainURI() + "\n" + uri.EncodeSIP002URI() + "\n" + uri.EncodeBase64URI())
+		if err != nil || len(list) != 3 {
+			t.Errorf("#%d list test failed for %q. Got: %v, %v", i, password, list, err)
+			continue
+		}
+
+		for _, decoded := range list {
+			if decoded.Auth.Password() != password {
+				t.Errorf("#%d list test failed. Expected: %q, Got: %q", i, password, decoded.Auth.Password())
+			}
+		}
+	}
+
+	// Unescaped plain URIs written by older versions and other tools still
+	// decode, unless they look exactly like an encoded password.
+	for i, ut := range []struct {
+		uri      string
+		password string
+	}{
+		{"ss://aes-256-gcm:p@ss:100%@example.com:8388", "p@ss:100%"},
+		{"ss://aes-256-gcm:50%41@1.2.3.4:443", "50%41"},
+		{"ss://aes-256-gcm:%zz%41@1.2.3.4:443", "%zz%41"},
+		{"ss://aes-256-gcm:p%40ss@1.2.3.4:443", "p@ss"},
+		{"ss://aes-256-gcm:100%25@1.2.3.4:443", "100%"},
+	} {
+		uri, err := ss.DecodePlainURI(ut.uri)
+		if err != nil || uri.Auth.Password() != ut.password {
+			t.Errorf("#%d test failed. Expected: %q, Got: %v, %v", i, ut.password, uri, err)
+		}
+	}
+}
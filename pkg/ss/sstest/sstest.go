@@ -0,0 +1,79 @@
+// Package sstest provides a local shadowsocks server for tests, in the
+// spirit of net/http/httptest.
+package sstest
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+// Server ... Shadowsocks server listening on the loopback interface.
+type Server struct {
+	// URI of the running server, its Remote is the listening address.
+	URI *ss.ShadowsocksURI
+
+	server *ss.ShadowsocksServer
+}
+
+// NewServer ... Start a server with method and password on a free port.
+// Panics on failure like httptest.NewServer.
+func NewServer(method, password string) *Server {
+	return StartServer(&ss.ShadowsocksURI{
+		Remote: ss.NewServer("127.0.0.1", 0),
+		Auth:   ss.NewAuthInfo(method, password),
+	})
+}
+
+// StartServer ... Start a server with the method, password and port of uri on
+// 127.0.0.1, relaying both TCP and UDP. Port 0 picks a free port. Panics on failure.
+func StartServer(uri *ss.ShadowsocksURI) *Server {
+	server, err := ss.NewShadowsocksServer(uri)
+	if err != nil {
+		panic(fmt.Sprintf("sstest: %v", err))
+	}
+
+	ln, pc, err := listen(uri.Remote.Port())
+	if err != nil {
+		panic(fmt.Sprintf("sstest: failed to listen: %v", err))
+	}
+
+	go server.Serve(ln)
+	go server.ServePacket(pc)
+
+	running := uri.Copy()
+	running.Remote = ss.NewServer("127.0.0.1", ln.Addr().(*net.TCPAddr).Port)
+
+	return &Server{URI: running, server: server}
+}
+
+// listen ... Listen on the same TCP and UDP port of 127.0.0.1. A free TCP
+// port may be taken for UDP, so port 0 is retried a few times.
+func listen(port int) (net.Listener, net.PacketConn, error) {
+	for i := 0; ; i++ {
+		ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		addr := ln.Addr().String()
+
+		pc, err := net.ListenPacket("udp", addr)
+		if err == nil {
+			return ln, pc, nil
+		}
+
+		ln.Close()
+
+		if port != 0 || i == 10 {
+			return nil, nil, err
+		}
+	}
+}
+
+// Close ... Stop the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
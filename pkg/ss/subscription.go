@@ -0,0 +1,57 @@
+package ss
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// DecodeSubscription ... Decode subscription, either a plain list of URIs, one
+// per line, or the same list encoded in base64 as served by most providers.
+func DecodeSubscription(data string) ([]*ShadowsocksURI, error) {
+	text, err := DecodeSubscriptionText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeURIList(text)
+}
+
+// DecodeSubscriptionText ... Returns the list of URIs of subscription, one per
+// line, with surrounding spaces trimmed. Base64 subscriptions are decoded.
+func DecodeSubscriptionText(data string) (string, error) {
+	data = strings.TrimSpace(data)
+
+	if data == "" || strings.Contains(data, "://") {
+		return data, nil
+	}
+
+	decoded, err := decodeBase64Any(strings.Join(strings.Fields(data), ""))
+	if err != nil {
+		return "", errors.New("invalid subscription")
+	}
+
+	return string(decoded), nil
+}
+
+// EncodeSubscription ... Encode servers as base64 subscription of SIP002 URIs.
+func EncodeSubscription(uris []*ShadowsocksURI) string {
+	lines := make([]string, len(uris))
+
+	for i, uri := range uris {
+		lines[i] = uri.EncodeSIP002URI()
+	}
+
+	return base64.StdEncoding.EncodeToString([]byte(strings.Join(lines, "\n") + "\n"))
+}
+
+// decodeBase64Any ... Decode standard or URL-safe base64, with or without padding.
+func decodeBase64Any(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+
+	return base64.RawStdEncoding.DecodeString(s)
+}
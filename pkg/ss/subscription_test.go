@@ -0,0 +1,49 @@
+package ss_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestSubscription(t *testing.T) {
+	uris := []*ss.ShadowsocksURI{
+		{Remote: ss.NewServer("192.168.100.1", 8888), Auth: ss.NewAuthInfo("bf-cfb", "test"), Tag: "a"},
+		{Remote: ss.NewServer("test.example.com", 8388), Auth: ss.NewAuthInfo("rc4-md5", "passwd"), Tag: "b",
+			Plugin: ss.NewPlugin("obfs-local", map[string]string{"obfs": "http"})},
+	}
+
+	encoded := ss.EncodeSubscription(uris)
+
+	for i, data := range []string{encoded, strings.TrimRight(encoded, "=") + "\n", "ss://YmYtY2ZiOnRlc3Q=@192.168.100.1:8888#a\n" +
+		"ss://cmM0LW1kNTpwYXNzd2Q=@test.example.com:8388/?plugin=obfs-local%3Bobfs%3Dhttp#b"} {
+		decoded, err := ss.DecodeSubscription(data)
+		if err != nil {
+			t.Errorf("#%d test failed. %v", i, err)
+			continue
+		}
+
+		if len(decoded) != len(uris) {
+			t.Errorf("#%d test failed. Expected %d servers, Got: %d", i, len(uris), len(decoded))
+			continue
+		}
+
+		for j := range uris {
+			if !decoded[j].Equal(uris[j]) {
+				t.Errorf("#%d test failed.\nExpected: %v\nGot     : %v", i, *uris[j], *decoded[j])
+			}
+		}
+	}
+
+	if _, err := ss.DecodeSubscription("not base64!"); err == nil {
+		t.Errorf("Expected error for invalid subscription")
+	}
+
+	plain := "ss://YmYtY2ZiOnRlc3Q=@192.168.100.1:8888#a\n\nss://bad"
+	for i, data := range []string{plain, "\n" + plain + "\n", "c3M6Ly9ZbVl0WTJaaU9uUmxjM1E9QDE5Mi4xNjguMTAwLjE6ODg4OCNhCgpzczovL2JhZA=="} {
+		if text, err := ss.DecodeSubscriptionText(data); err != nil || text != plain {
+			t.Errorf("#%d test failed. Expected: %q, Got: %q, %v", i, plain, text, err)
+		}
+	}
+}
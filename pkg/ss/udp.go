@@ -0,0 +1,265 @@
+package ss
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+)
+
+// udpBufferSize ... Large enough for any UDP packet.
+const udpBufferSize = 64 * 1024
+
+// udpTimeout ... Idle time after which a UDP association is dropped.
+const udpTimeout = 60 * time.Second
+
+// sealPacket ... Encrypt a UDP packet as [salt][payload][tag]. Every packet
+// has its own salt, so the nonce is always zero.
+func (c *AEADCipher) sealPacket(payload []byte) ([]byte, error) {
+	salt := make([]byte, c.saltSize())
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := c.newAEAD(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(salt, make([]byte, aead.NonceSize()), payload, nil), nil
+}
+
+// openPacket ... Decrypt a UDP packet sealed by sealPacket.
+func (c *AEADCipher) openPacket(packet []byte) ([]byte, error) {
+	if len(packet) < c.saltSize() {
+		return nil, errAEADAuth
+	}
+
+	aead, err := c.newAEAD(packet[:c.saltSize()])
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := aead.Open(nil, make([]byte, aead.NonceSize()), packet[c.saltSize():], nil)
+	if err != nil {
+		return nil, errAEADAuth
+	}
+
+	return payload, nil
+}
+
+// splitSocksAddr ... Split a packet into its leading SOCKS5 address and the data.
+func splitSocksAddr(packet []byte) (string, []byte, error) {
+	r := bytes.NewReader(packet)
+
+	addr, err := ReadSocksAddr(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return addr, packet[len(packet)-r.Len():], nil
+}
+
+// udpSession ... Outbound socket of a client of ServePacket.
+type udpSession struct {
+	outbound net.PacketConn
+	active   time.Time // Last packet of the client
+}
+
+// ServePacket ... Relay UDP packets received on pc until Close is called, pc is closed by Close.
+func (s *ShadowsocksServer) ServePacket(pc net.PacketConn) error {
+	if !s.listeners.add(pc) {
+		return errClosed
+	}
+
+	// Sessions are looked up, refreshed, written to and closed under mu, so a
+	// packet never goes to a socket closed for being idle.
+	var mu sync.Mutex
+	nat := make(map[string]*udpSession) // client address to its session
+
+	buf := make([]byte, udpBufferSize)
+
+	for {
+		n, client, err := pc.ReadFrom(buf)
+		if err != nil {
+			return s.listeners.remove(pc, err)
+		}
+
+		payload, err := s.cipher.openPacket(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		target, data, err := splitSocksAddr(payload)
+		if err != nil {
+			continue
+		}
+
+		targetAddr, err := net.ResolveUDPAddr("udp", target)
+		if err != nil {
+			continue
+		}
+
+		mu.Lock()
+
+		session, ok := nat[client.String()]
+		if !ok {
+			outbound, err := net.ListenPacket("udp", "")
+			if err != nil {
+				mu.Unlock()
+				continue
+			}
+
+			session = &udpSession{outbound: outbound}
+			nat[client.String()] = session
+
+			go func(client net.Addr, session *udpSession) {
+				s.relayReplies(pc, client, session.outbound, func() bool {
+					mu.Lock()
+					defer mu.Unlock()
+
+					return time.Since(session.active) < udpTimeout
+				})
+
+				mu.Lock()
+				delete(nat, client.String())
+				session.outbound.Close()
+				mu.Unlock()
+			}(client, session)
+		}
+
+		session.active = time.Now()
+		session.outbound.SetReadDeadline(session.active.Add(udpTimeout))
+		session.outbound.WriteTo(data, targetAddr)
+
+		mu.Unlock()
+	}
+}
+
+// relayReplies ... Send packets arriving at outbound back to client until
+// reading fails, or times out while active reports the client idle. The caller
+// closes outbound.
+func (s *ShadowsocksServer) relayReplies(pc net.PacketConn, client net.Addr, outbound net.PacketConn, active func() bool) {
+	if !s.listeners.add(outbound) {
+		return
+	}
+	defer s.listeners.remove(outbound, nil)
+
+	buf := make([]byte, udpBufferSize)
+
+	for {
+		n, from, err := outbound.ReadFrom(buf)
+		if err != nil {
+			// The client may have sent a packet since the deadline passed.
+			if ne, ok := err.(net.Error); ok && ne.Timeout() && active() {
+				continue
+			}
+
+			return
+		}
+
+		payload, err := AppendSocksAddr(nil, from.String())
+		if err != nil {
+			continue
+		}
+
+		packet, err := s.cipher.sealPacket(append(payload, buf[:n]...))
+		if err != nil {
+			continue
+		}
+
+		pc.WriteTo(packet, client)
+	}
+}
+
+// associate ... Handle SOCKS5 UDP ASSOCIATE of conn: relay datagrams between
+// the SOCKS client and the shadowsocks server while conn stays open.
+func (c *ShadowsocksClient) associate(conn net.Conn) {
+	local, err := net.ListenUDP("udp", &net.UDPAddr{IP: conn.LocalAddr().(*net.TCPAddr).IP})
+	if err != nil {
+		conn.Write(socksReply(socksReplyGeneralFailure))
+		return
+	}
+	defer local.Close()
+
+	remote, err := net.DialTimeout("udp", dialAddress(c.config.Remote), c.timeout())
+	if err != nil {
+		conn.Write(socksReply(socksReplyGeneralFailure))
+		return
+	}
+	defer remote.Close()
+
+	reply, err := socksReplyAddr(socksReplySucceeded, local.LocalAddr().String())
+	if err != nil {
+		return
+	}
+
+	if _, err := conn.Write(reply); err != nil {
+		return
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	var mu sync.Mutex
+	var peer net.Addr // SOCKS client, known after its first datagram
+
+	clientIP := conn.RemoteAddr().(*net.TCPAddr).IP
+
+	// SOCKS client to server: [RSV RSV FRAG][address][data].
+	go func() {
+		buf := make([]byte, udpBufferSize)
+
+		for {
+			n, from, err := local.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			if !from.(*net.UDPAddr).IP.Equal(clientIP) || n < 3 || buf[2] != 0 {
+				continue // only our client, and no fragments
+			}
+
+			mu.Lock()
+			peer = from
+			mu.Unlock()
+
+			packet, err := c.cipher.sealPacket(buf[3:n])
+			if err != nil {
+				continue
+			}
+
+			remote.Write(packet)
+		}
+	}()
+
+	// Server to SOCKS client.
+	go func() {
+		buf := make([]byte, udpBufferSize)
+
+		for {
+			n, err := remote.Read(buf)
+			if err != nil {
+				return
+			}
+
+			payload, err := c.cipher.openPacket(buf[:n])
+			if err != nil {
+				continue
+			}
+
+			mu.Lock()
+			to := peer
+			mu.Unlock()
+
+			if to != nil {
+				local.WriteTo(append([]byte{0, 0, 0}, payload...), to)
+			}
+		}
+	}()
+
+	// The association lasts as long as the TCP connection.
+	io.Copy(ioutil.Discard, conn)
+}
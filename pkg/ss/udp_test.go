@@ -0,0 +1,142 @@
+package ss_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+	"github.com/vgxbj/ssuri/pkg/ss/sstest"
+)
+
+// startClientWithMode ... Start SOCKS5 client of uri relaying by mode, returns its address.
+func startClientWithMode(t *testing.T, uri *ss.ShadowsocksURI, mode string) string {
+	t.Helper()
+
+	scc := ss.ToShadowsocksClientConfig(uri)
+	scc.Mode = mode
+
+	client, err := ss.NewShadowsocksClient(scc)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	go client.Serve(ln)
+	t.Cleanup(func() { client.Close() })
+
+	return ln.Addr().String()
+}
+
+// socksRequest ... Send a SOCKS5 request for cmd and addr, returns the reply code and bound address.
+func socksRequest(t *testing.T, conn net.Conn, cmd byte, addr string) (byte, string) {
+	t.Helper()
+
+	request, _ := ss.AppendSocksAddr([]byte{5, 1, 0, 5, cmd, 0}, addr)
+	conn.Write(request)
+
+	reply := make([]byte, 5)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	bound, err := ss.ReadSocksAddr(conn)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return reply[3], bound
+}
+
+func TestUDPRelay(t *testing.T) {
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer echo.Close()
+
+	go func() {
+		buf := make([]byte, 2048)
+
+		for {
+			n, from, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			echo.WriteTo(buf[:n], from)
+		}
+	}()
+
+	server := sstest.NewServer("chacha20-ietf-poly1305", "correct horse")
+	defer server.Close()
+
+	proxy := startClientWithMode(t, server.URI, ss.ModeTCPAndUDP)
+
+	conn, err := net.Dial("tcp", proxy)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer conn.Close()
+
+	code, bound := socksRequest(t, conn, 3, "0.0.0.0:0")
+	if code != 0 {
+		t.Fatalf("Expected UDP ASSOCIATE to succeed, Got: %d", code)
+	}
+
+	udp, err := net.Dial("udp", bound)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer udp.Close()
+
+	packet, _ := ss.AppendSocksAddr([]byte{0, 0, 0}, echo.LocalAddr().String())
+	packet = append(packet, "ping"...)
+
+	buf := make([]byte, 2048)
+
+	for i := 0; i < 3; i++ {
+		udp.Write(packet)
+		udp.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+		n, err := udp.Read(buf)
+		if err != nil || !bytes.Equal(buf[:n], packet) {
+			t.Errorf("#%d test failed. Expected echo, Got: %q, %v", i, buf[:n], err)
+		}
+	}
+
+	// Modes restrict the commands.
+	tests := []struct {
+		mode string
+		cmd  byte
+	}{
+		{ss.ModeTCPOnly, 3},
+		{ss.ModeUDPOnly, 1},
+	}
+
+	for i, ut := range tests {
+		conn, err := net.Dial("tcp", startClientWithMode(t, server.URI, ut.mode))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if code, _ := socksRequest(t, conn, ut.cmd, echo.LocalAddr().String()); code != 7 {
+			t.Errorf("#%d test failed. Expected command not supported, Got: %d", i, code)
+		}
+
+		conn.Close()
+	}
+
+	scc := ss.ToShadowsocksClientConfig(server.URI)
+	scc.Mode = "udp"
+
+	if _, err := ss.NewShadowsocksClient(scc); err == nil {
+		t.Errorf("Expected error for invalid mode")
+	}
+}
@@ -3,7 +3,10 @@ package ss
 import (
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"net"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -52,6 +55,16 @@ func (s *Server) Port() int {
 	return s.port
 }
 
+// SetHostname ... Sets hostname.
+func (s *Server) SetHostname(hostname string) {
+	s.hostname = hostname
+}
+
+// SetPort ... Sets port.
+func (s *Server) SetPort(port int) {
+	s.port = port
+}
+
 // String ... Encode remote server address.
 func (s *Server) String() string {
 	if strings.Contains(s.hostname, ":") {
@@ -78,6 +91,16 @@ func (auth *AuthInfo) Password() string {
 	return auth.password
 }
 
+// SetMethod ... Sets method of authentication information.
+func (auth *AuthInfo) SetMethod(method string) {
+	auth.method = method
+}
+
+// SetPassword ... Sets password of authentication information.
+func (auth *AuthInfo) SetPassword(password string) {
+	auth.password = password
+}
+
 // String ... Return the encoded authentication information.
 func (auth *AuthInfo) String() string {
 	return auth.method + ":" + auth.password
@@ -99,6 +122,20 @@ func (plugin *PluginInfo) Options() map[string]string {
 	return plugin.options
 }
 
+// SetOption ... Sets option k of plugin to v, "" for a flag without value.
+func (plugin *PluginInfo) SetOption(k, v string) {
+	if plugin.options == nil {
+		plugin.options = map[string]string{}
+	}
+
+	plugin.options[k] = v
+}
+
+// DeleteOption ... Removes option k of plugin.
+func (plugin *PluginInfo) DeleteOption(k string) {
+	delete(plugin.options, k)
+}
+
 // OptionsString ... Encode options to string.
 func (plugin *PluginInfo) OptionsString() string {
 	// Safely return "" for empty map.
@@ -108,38 +145,73 @@ func (plugin *PluginInfo) OptionsString() string {
 
 	options := []string{}
 
-	for k, v := range plugin.Options() {
-		options = append(options, k+"="+v)
+	for _, k := range plugin.optionKeys() {
+		options = append(options, plugin.option(k))
 	}
 
 	return strings.Join(options, ";")
 }
 
-// String ... Return the encoded plugin information.
-func (plugin *PluginInfo) String() string {
-	builder := url.Values{}
+// optionKeys ... Returns option keys of plugin in sorted order.
+func (plugin *PluginInfo) optionKeys() []string {
+	keys := make([]string, 0, len(plugin.options))
 
-	var options = plugin.name
+	for k := range plugin.options {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
 
-	for k, v := range plugin.options {
-		options += ";" + k + "=" + v
+// option ... Encode option k as "k=v", or "k" alone for flags without value.
+func (plugin *PluginInfo) option(k string) string {
+	if plugin.options[k] == "" {
+		return escapePluginOption(k)
 	}
 
+	return escapePluginOption(k) + "=" + escapePluginOption(plugin.options[k])
+}
+
+// pluginOptionEscaper ... Backslash escapes of SIP003 option names and values.
+var pluginOptionEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, "=", `\=`)
+
+// escapePluginOption ... Escape '\\', ';' and '=' as SIP003 requires.
+func escapePluginOption(s string) string {
+	return pluginOptionEscaper.Replace(s)
+}
+
+// String ... Return the encoded plugin information.
+func (plugin *PluginInfo) String() string {
+	builder := url.Values{}
+
 	// SIP002 URI scheme only supports one plugin.
 	// See: https://shadowsocks.org/en/spec/SIP002-URI-Scheme.html
-	builder.Add("plugin", options)
+	builder.Add("plugin", plugin.encode())
 
 	return "/?" + builder.Encode()
 }
 
+// encode ... Encode plugin as "<name>[;<options>]", the inverse of parsePlugin.
+func (plugin *PluginInfo) encode() string {
+	var options = escapePluginOption(plugin.name)
+
+	for _, k := range plugin.optionKeys() {
+		options += ";" + plugin.option(k)
+	}
+
+	return options
+}
+
 // EncodeSIP002URI ... Encode shadowsocks configuration into SIP002 URI.
 func (uri *ShadowsocksURI) EncodeSIP002URI() string {
 	// Encode auth information.
 	auth := uri.Auth.String()
 	auth = base64.URLEncoding.EncodeToString([]byte(auth))
 
-	// Add hostname, port.
-	wrappedHost := uri.Remote.String()
+	// Add hostname, port. The zone of an IPv6 address needs its '%' escaped.
+	wrappedHost := strings.Replace(uri.Remote.String(), "%", "%25", -1)
 
 	// Encode plugin parameters.
 	var plugin = ""
@@ -155,6 +227,8 @@ func (uri *ShadowsocksURI) EncodeSIP002URI() string {
 }
 
 // EncodeBase64URI ... Encode shadowsocks configuration into base64 URI (legacy).
+// The password is kept raw inside base64 as other clients expect, decoders
+// split at the last '@' and the first ':' so any password survives.
 func (uri *ShadowsocksURI) EncodeBase64URI() string {
 	auth := uri.Auth.String()
 
@@ -171,23 +245,29 @@ func (uri *ShadowsocksURI) EncodeBase64URI() string {
 }
 
 // EncodePlainURI ... Encode shadowsocks configuration into plain URI.
+// Method and password are percent-encoded, so reserved characters like '@',
+// ':' and '#' survive.
 func (uri *ShadowsocksURI) EncodePlainURI() string {
-	auth := uri.Auth.String()
+	auth := url.UserPassword(uri.Auth.method, uri.Auth.password).String()
 
 	wrappedHost := uri.Remote.String()
 
 	return "ss://" + auth + "@" + wrappedHost
 }
 
-// encodeFragment ... Encode fragment.
+// encodeFragment ... Encode fragment, percent-encoding what a URI fragment
+// cannot hold. Brackets of IPv6 addresses, common in tags, are kept.
 func (uri *ShadowsocksURI) encodeFragment() string {
 	if uri.Tag != "" {
-		return "#" + uri.Tag
+		return "#" + fragmentBrackets.Replace((&url.URL{Fragment: uri.Tag}).EscapedFragment())
 	}
 
 	return ""
 }
 
+// fragmentBrackets ... Undo escaping of brackets, a literal "%5B" is escaped as "%255B".
+var fragmentBrackets = strings.NewReplacer("%5B", "[", "%5D", "]")
+
 // DecodeSIP002URI ... Decode SIP002 shadowsocks URI.
 func DecodeSIP002URI(uri string) (*ShadowsocksURI, error) {
 	// Omit "ss://"
@@ -211,7 +291,7 @@ func DecodeSIP002URI(uri string) (*ShadowsocksURI, error) {
 	}
 
 	// decodedAuth := <method>:<password>
-	decodedAuthStr, err := base64.URLEncoding.DecodeString(authStr)
+	decodedAuthStr, err := decodeBase64Any(authStr)
 	if err != nil {
 		return nil, errors.New("invalid base64 encoded <auth>")
 	}
@@ -261,9 +341,9 @@ func DecodeBase64URI(uri string) (*ShadowsocksURI, error) {
 	}
 
 	// decoded := <auth>@<hostname>:<port>
-	decoded, err := base64.RawURLEncoding.DecodeString(s)
+	decoded, err := decodeBase64Any(s)
 	if err != nil {
-		return nil, err
+		return nil, errors.New("invalid base64 encoded URI")
 	}
 
 	// authStr := <auth>
@@ -294,11 +374,17 @@ func DecodeBase64URI(uri string) (*ShadowsocksURI, error) {
 // DecodePlainURI ... Decode plain shadowsocks URI.
 func DecodePlainURI(uri string) (*ShadowsocksURI, error) {
 	// Omit "ss://"
+	// s := <auth>@<hostname>:<port> [ "#" <tag> ]
 	s, ok := checkPrefixAndTrim(uri, "ss://")
 	if !ok {
 		return nil, errors.New("invalid <scheme>")
 	}
 
+	s, tag, err := parseTag(s)
+	if err != nil {
+		return nil, err
+	}
+
 	// authStr := <auth>
 	// s := <hostname>:<port>
 	authStr, s, err := splitAuthAndHost(s)
@@ -311,6 +397,8 @@ func DecodePlainURI(uri string) (*ShadowsocksURI, error) {
 		return nil, err
 	}
 
+	auth.method, auth.password = unescapeUserinfo(auth.method, auth.password)
+
 	host, err := parseRemoteServer(s)
 	if err != nil {
 		return nil, err
@@ -319,11 +407,128 @@ func DecodePlainURI(uri string) (*ShadowsocksURI, error) {
 	return &ShadowsocksURI{
 		Remote: host,
 		Auth:   auth,
-		Tag:    "",
+		Tag:    tag,
 		Plugin: nil,
 	}, nil
 }
 
+// unescapeUserinfo ... Decode method and password percent-encoded by
+// EncodePlainURI. Userinfo is taken as it is unless it is exactly what
+// EncodePlainURI writes, so raw passwords of older versions and other tools
+// keep their '%', e.g. "50%41". A raw password which happens to be such an
+// encoding is decoded nevertheless, "100%25" becomes "100%".
+func unescapeUserinfo(method, password string) (string, string) {
+	m, err := url.PathUnescape(method)
+	if err != nil {
+		return method, password
+	}
+
+	p, err := url.PathUnescape(password)
+	if err != nil || url.UserPassword(m, p).String() != method+":"+password {
+		return method, password
+	}
+
+	return m, p
+}
+
+// URI flavors, named like their formats.
+const (
+	FlavorSIP002 = "sip002" // SIP002 URI
+	FlavorLegacy = "legacy" // Legacy base64 encoded URI
+	FlavorPlain  = "plain"  // Plain URI
+)
+
+// DetectURIFlavor ... Returns the scheme URI is written in, FlavorSIP002,
+// FlavorLegacy or FlavorPlain, or "" if it is no shadowsocks URI at all.
+func DetectURIFlavor(uri string) string {
+	s, ok := checkPrefixAndTrim(uri, "ss://")
+	if !ok {
+		return ""
+	}
+
+	s, _, _ = parseTag(s)
+
+	// Legacy URI hides everything including '@' inside base64.
+	splitIndex := strings.LastIndexByte(s, '@')
+	if splitIndex == -1 {
+		return FlavorLegacy
+	}
+
+	// Plain URI has a clear text <method>:<password>, base64 never contains ':'.
+	if strings.IndexByte(s[:splitIndex], ':') != -1 {
+		return FlavorPlain
+	}
+
+	return FlavorSIP002
+}
+
+// DecodeURI ... Decode shadowsocks URI, detecting SIP002, legacy base64 or plain scheme.
+func DecodeURI(uri string) (*ShadowsocksURI, error) {
+	switch DetectURIFlavor(uri) {
+	case FlavorLegacy:
+		return DecodeBase64URI(uri)
+	case FlavorPlain:
+		return DecodePlainURI(uri)
+	case FlavorSIP002:
+		return DecodeSIP002URI(uri)
+	}
+
+	return nil, errors.New("invalid <scheme>")
+}
+
+// FitsFlavor ... Reports whether the named flavor can hold uri. Only SIP002
+// URIs carry plugins.
+func (uri *ShadowsocksURI) FitsFlavor(flavor string) bool {
+	switch flavor {
+	case FlavorSIP002:
+		return true
+	case FlavorLegacy, FlavorPlain:
+		return uri.Plugin == nil
+	}
+
+	return false
+}
+
+// EncodeFlavor ... Encode shadowsocks URI in the named flavor. Unlike
+// EncodePlainURI(), plain URIs keep the tag, which DecodePlainURI() reads.
+// Flavors unable to hold uri, see FitsFlavor(), fall back to SIP002.
+func (uri *ShadowsocksURI) EncodeFlavor(flavor string) string {
+	if !uri.FitsFlavor(flavor) {
+		return uri.EncodeSIP002URI()
+	}
+
+	switch flavor {
+	case FlavorLegacy:
+		return uri.EncodeBase64URI()
+	case FlavorPlain:
+		return uri.EncodePlainURI() + uri.encodeFragment()
+	}
+
+	return uri.EncodeSIP002URI()
+}
+
+// DecodeURIList ... Decode a list of shadowsocks URIs, one per line.
+// Blank lines are skipped.
+func DecodeURIList(data string) ([]*ShadowsocksURI, error) {
+	uris := []*ShadowsocksURI{}
+
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		uri, err := DecodeURI(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+
+		uris = append(uris, uri)
+	}
+
+	return uris, nil
+}
+
 // checkPrefixAndTrim ... Check given prefix and remove it.
 func checkPrefixAndTrim(s, prefix string) (string, bool) {
 	if strings.HasPrefix(s, prefix) {
@@ -333,17 +538,22 @@ func checkPrefixAndTrim(s, prefix string) (string, bool) {
 	return s, false
 }
 
-// parseTag ... Parse tag.
+// parseTag ... Parse tag, the fragment after the first '#'. Tags are
+// percent-decoded like any URI fragment, so a literal "%25" in a tag written
+// by hand stands for '%', as it does in tags of the encoders. Tags which are
+// no valid encoding, e.g. "100%", are taken as they are.
 func parseTag(uri string) (string, string, error) {
-	splitURI := strings.Split(uri, "#")
+	splitIndex := strings.IndexByte(uri, '#')
+	if splitIndex == -1 {
+		return uri, "", nil
+	}
 
-	if len(splitURI) == 2 {
-		return splitURI[0], splitURI[1], nil
-	} else if len(splitURI) == 1 {
-		return splitURI[0], "", nil
+	tag := uri[splitIndex+1:]
+	if unescaped, err := url.PathUnescape(tag); err == nil {
+		tag = unescaped
 	}
 
-	return "", "", errors.New("invalid URI")
+	return uri[:splitIndex], tag, nil
 }
 
 // splitAuthAndHost ... Split authentication information and hostname.
@@ -391,74 +601,134 @@ func parseRemoteServer(hostStr string) (*Server, error) {
 	}
 
 	port, err := strconv.Atoi(hostStr[splitIndex+1:])
-	if err != nil {
+	if err != nil || port < 1 || port > 65535 {
 		return nil, errors.New("invalid <port>")
 	}
 
-	return &Server{hostStr[:splitIndex], port}, nil
+	// IPv6 addresses come in brackets, which are not part of the hostname.
+	hostname := hostStr[:splitIndex]
+	if strings.HasPrefix(hostname, "[") && strings.HasSuffix(hostname, "]") {
+		hostname = hostname[1 : len(hostname)-1]
+
+		if !isIPv6(hostname) {
+			return nil, errors.New("invalid <hostname>")
+		}
+	} else if hostname == "" || strings.ContainsAny(hostname, "[]:") {
+		return nil, errors.New("invalid <hostname>")
+	}
+
+	return &Server{hostname, port}, nil
+}
+
+// isIPv6 ... Reports whether s is an IPv6 address, optionally with a zone.
+func isIPv6(s string) bool {
+	ip := net.ParseIP(strings.SplitN(s, "%", 2)[0])
+
+	return ip != nil && strings.Contains(s, ":") && !strings.ContainsAny(s, "[]")
 }
 
-// parsePlugin ... Parse plugin (used in SIP002 URI scheme).
+// parsePlugin ... Parse plugin (used in SIP002 URI scheme),
+// "<name>[;<options>]" with options as in ParsePluginOpts.
 func parsePlugin(pluginStr string) (*PluginInfo, error) {
 	if pluginStr == "" {
 		return nil, nil
 	}
 
-	nameAndOptions := strings.Split(pluginStr, ";")
+	name, optionsStr := splitPluginOption(pluginStr, ';')
 
-	if len(nameAndOptions) < 2 {
+	name, ok := unescapePluginOption(name)
+	if !ok || name == "" {
 		return nil, errors.New("invalid <plugin>")
 	}
 
-	var name string
-	options := make(map[string]string)
-
-	for i, o := range nameAndOptions {
-		if i == 0 {
-			// name of plugin
-			name = o
-			continue
-		}
+	options, err := ParsePluginOpts(optionsStr)
+	if err != nil {
+		return nil, errors.New("invalid <plugin>")
+	}
 
-		kv := strings.Split(o, "=")
-		if len(kv) == 2 {
-			options[kv[0]] = kv[1]
-		}
+	if options == nil {
+		options = make(map[string]string)
 	}
 
 	return NewPlugin(name, options), nil
 }
 
-// ParsePluginOpts ... Parse plugin options.
+// ParsePluginOpts ... Parse plugin options "<key>=<value>;<flag>;...", where
+// '\\' escapes ';', '=' and '\\' as in SIP003. A value extends to the next
+// unescaped ';', so it may contain unescaped '=', e.g. base64 keys.
 func ParsePluginOpts(s string) (map[string]string, error) {
 	if s == "" {
 		return nil, nil
 	}
 
 	opt := make(map[string]string)
-	opts := strings.Split(s, ";")
 
-	for _, o := range opts {
-		kv := strings.Split(o, "=")
-		if len(kv) == 2 {
-			opt[kv[0]] = kv[1]
-		} else {
+	for s != "" {
+		var o string
+		o, s = splitPluginOption(s, ';')
+
+		// Tolerate empty options, e.g. a trailing ";".
+		if o == "" {
+			continue
+		}
+
+		k, v := splitPluginOption(o, '=')
+
+		key, ok := unescapePluginOption(k)
+		if !ok || key == "" {
+			return nil, errors.New("invalid <plugin_opts>")
+		}
+
+		value, ok := unescapePluginOption(v)
+		if !ok {
 			return nil, errors.New("invalid <plugin_opts>")
 		}
+
+		opt[key] = value
 	}
 
 	return opt, nil
 }
 
-// ToShadowsocksClientConfig ... Convert shadowsocks URI to client configuration.
-func ToShadowsocksClientConfig(uri *ShadowsocksURI) *ShadowsocksClientConfig {
-	return &ShadowsocksClientConfig{
-		Remote:   uri.Remote,
-		Auth:     uri.Auth,
-		Local:    NewServer("127.0.0.1", 1080),
-		Timeout:  300,
-		FastOpen: false,
-		Workers:  1,
-		Plugin:   uri.Plugin,
+// splitPluginOption ... Split s at the first sep not escaped by '\\', the
+// second part is empty if there is none.
+func splitPluginOption(s string, sep byte) (string, string) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:]
+		}
+	}
+
+	return s, ""
+}
+
+// unescapePluginOption ... Remove backslash escapes, fails on a trailing '\\'.
+func unescapePluginOption(s string) (string, bool) {
+	if strings.IndexByte(s, '\\') == -1 {
+		return s, true
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			if i == len(s) {
+				return "", false
+			}
+		}
+
+		b.WriteByte(s[i])
 	}
+
+	return b.String(), true
+}
+
+// ToShadowsocksClientConfig ... Convert shadowsocks URI to client configuration
+// using DefaultClientOptions().
+func ToShadowsocksClientConfig(uri *ShadowsocksURI) *ShadowsocksClientConfig {
+	return ToShadowsocksClientConfigWithOptions(uri, DefaultClientOptions())
 }
@@ -0,0 +1,153 @@
+package ss
+
+import (
+	"strings"
+)
+
+// FieldError ... Problem of a single JSON field.
+type FieldError struct {
+	Field  string // JSON key, e.g. "server_port"
+	Reason string // e.g. "missing"
+}
+
+// Error ... Implements error.
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// ValidationError ... Every problem found while validating a JSON configuration.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+// Error ... Implements error.
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Errors))
+
+	for i, fe := range e.Errors {
+		problems[i] = fe.Error()
+	}
+
+	return "invalid configuration: " + strings.Join(problems, ", ")
+}
+
+// Missing ... Returns required fields missing from the configuration.
+func (e *ValidationError) Missing() []string {
+	fields := []string{}
+
+	for _, fe := range e.Errors {
+		if fe.Reason == reasonMissing {
+			fields = append(fields, fe.Field)
+		}
+	}
+
+	return fields
+}
+
+const reasonMissing = "missing"
+
+// add ... Record a problem of field.
+func (e *ValidationError) add(field, reason string) {
+	e.Errors = append(e.Errors, &FieldError{field, reason})
+}
+
+// validateClientJSON ... Check required fields of a client JSON configuration.
+// Returns nil if there is no problem.
+func validateClientJSON(clientJSON *ShadowsocksClientJSON) error {
+	v := &ValidationError{}
+
+	if clientJSON.Server == "" {
+		v.add("server", reasonMissing)
+	}
+
+	if clientJSON.ServerPort == 0 {
+		v.add("server_port", reasonMissing)
+	} else if clientJSON.ServerPort < 0 || clientJSON.ServerPort > 65535 {
+		v.add("server_port", "out of range")
+	}
+
+	if clientJSON.Method == "" {
+		v.add("method", reasonMissing)
+	}
+
+	if clientJSON.Password == "" {
+		v.add("password", reasonMissing)
+	}
+
+	if clientJSON.LocalPort < 0 || clientJSON.LocalPort > 65535 {
+		v.add("local_port", "out of range")
+	}
+
+	if _, err := ParsePluginOpts(clientJSON.PluginOpts); err != nil {
+		v.add("plugin_opts", "invalid")
+	}
+
+	if len(v.Errors) != 0 {
+		return v
+	}
+
+	return nil
+}
+
+// relaxJSON ... Strip "//" and "/* */" comments and trailing commas before
+// "}" or "]", which shadowsocks-libev tolerates, leaving strings untouched.
+func relaxJSON(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	pendingComma := -1 // index in out of a comma that may be trailing
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		switch {
+		case c == '"':
+			// Copy string literal verbatim.
+			pendingComma = -1
+			out = append(out, c)
+
+			for i++; i < len(data); i++ {
+				out = append(out, data[i])
+
+				if data[i] == '\\' && i+1 < len(data) {
+					i++
+					out = append(out, data[i])
+				} else if data[i] == '"' {
+					break
+				}
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := strings.Index(string(data[i+2:]), "*/")
+			if end == -1 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+
+			out = append(out, ' ')
+		case c == ',':
+			pendingComma = len(out)
+			out = append(out, c)
+		case c == '}' || c == ']':
+			if pendingComma != -1 {
+				out[pendingComma] = ' '
+				pendingComma = -1
+			}
+
+			out = append(out, c)
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			out = append(out, c)
+		default:
+			pendingComma = -1
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,68 @@
+package ss_test
+
+import (
+	"testing"
+
+	"github.com/vgxbj/ssuri/pkg/ss"
+)
+
+func TestDecodeJSONValidation(t *testing.T) {
+	tests := []struct {
+		data    string
+		missing []string
+	}{
+		{`{}`, []string{"server", "server_port", "method", "password"}},
+		{`{"server": "some_host", "method": "bf-cfb"}`, []string{"server_port", "password"}},
+		{`{"server": "some_host", "server_port": 8118, "password": "test"}`, []string{"method"}},
+	}
+
+	for i, ut := range tests {
+		_, err := ss.DecodeJSON([]byte(ut.data))
+
+		verr, ok := err.(*ss.ValidationError)
+		if !ok {
+			t.Errorf("#%d test failed. Expected *ValidationError, Got: %v", i, err)
+			continue
+		}
+
+		if !equalStrings(verr.Missing(), ut.missing) {
+			t.Errorf("#%d test failed. Expected: %v, Got: %v", i, ut.missing, verr.Missing())
+		}
+	}
+
+	_, err := ss.DecodeJSON([]byte(`{"server": "some_host", "server_port": 70000, "method": "bf-cfb", "password": "test"}`))
+	if verr, ok := err.(*ss.ValidationError); !ok || len(verr.Errors) != 1 || verr.Errors[0].Field != "server_port" {
+		t.Errorf("Expected out of range server_port, Got: %v", err)
+	}
+}
+
+func TestDecodeJSONDefaults(t *testing.T) {
+	data := `{
+    // Hong Kong
+    "server": "some_host", /* primary */
+    "server_port": 8118,
+    "password": "te//st/*,}",
+    "method": "bf-cfb",
+    "plugin_opts": "obfs=http;",
+    "plugin": "obfs-local",
+}`
+
+	cc, err := ss.DecodeJSON([]byte(data))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	expected := &ss.ShadowsocksClientConfig{
+		Remote:   ss.NewServer("some_host", 8118),
+		Local:    ss.NewServer("127.0.0.1", 1080),
+		Auth:     ss.NewAuthInfo("bf-cfb", "te//st/*,}"),
+		Timeout:  300,
+		FastOpen: false,
+		Workers:  1,
+		Plugin:   ss.NewPlugin("obfs-local", map[string]string{"obfs": "http"}),
+	}
+
+	if !checkClientConfig(cc, expected) {
+		t.Errorf("Expected:\n%v,\nGot:\n%v", *expected, *cc)
+	}
+}